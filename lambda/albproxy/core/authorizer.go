@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// Keys of the authorizer context values describing the user, as set by the
+// httpadapter Authorizer.
+const (
+	AuthorizerEmailKey = "email"
+	AuthorizerNameKey  = "name"
+	AuthorizerPhoneKey = "phone"
+	AuthorizerRoleKey  = "role"
+)
+
+// AuthorizerValue returns a value of a Lambda authorizer context as a string, or ""
+// if it is missing. API Gateway delivers the values as strings, numbers or booleans.
+func AuthorizerValue(authorizer map[string]interface{}, key string) string {
+	switch v := authorizer[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// GetAuthorizerFromContext returns the Lambda authorizer context of a REST, HTTP or
+// WebSocket API request converted by the RequestAccessors. It returns false for other
+// requests and requests without a Lambda authorizer.
+func GetAuthorizerFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	var authorizer map[string]interface{}
+	switch rc := ctx.Value(ctxKey{}).(type) {
+	case requestContext:
+		authorizer = rc.gatewayProxyContext.Authorizer
+	case requestContextV2:
+		if rc.gatewayProxyContext.Authorizer != nil {
+			authorizer = rc.gatewayProxyContext.Authorizer.Lambda
+		}
+	case requestContextWebsocket:
+		authorizer, _ = rc.websocketContext.Authorizer.(map[string]interface{})
+	}
+	return authorizer, authorizer != nil
+}
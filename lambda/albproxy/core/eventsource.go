@@ -0,0 +1,156 @@
+package core
+
+import (
+	"encoding/json"
+)
+
+// EventSource identifies the AWS service that produced a Lambda event.
+type EventSource int
+
+const (
+	EventSourceUnknown EventSource = iota
+	EventSourceALB
+	EventSourceAPIGatewayV1
+	EventSourceAPIGatewayV2
+	EventSourceSQS
+	EventSourceSNS
+	EventSourceS3
+	EventSourceEventBridge
+	EventSourceWebsocket
+	EventSourceVPCLattice
+	EventSourceVPCLatticeV2
+)
+
+func (s EventSource) String() string {
+	switch s {
+	case EventSourceALB:
+		return "alb"
+	case EventSourceAPIGatewayV1:
+		return "apigateway-v1"
+	case EventSourceAPIGatewayV2:
+		return "apigateway-v2"
+	case EventSourceSQS:
+		return "sqs"
+	case EventSourceSNS:
+		return "sns"
+	case EventSourceS3:
+		return "s3"
+	case EventSourceEventBridge:
+		return "eventbridge"
+	case EventSourceWebsocket:
+		return "websocket"
+	case EventSourceVPCLattice:
+		return "vpc-lattice"
+	case EventSourceVPCLatticeV2:
+		return "vpc-lattice-v2"
+	}
+	return "unknown"
+}
+
+// IsProxy reports whether the source is an HTTP front end served by SwitchableRequest.
+func (s EventSource) IsProxy() bool {
+	return s == EventSourceALB || s == EventSourceAPIGatewayV1 || s == EventSourceAPIGatewayV2
+}
+
+// DetectEventSource inspects a raw Lambda event and returns its source. WebSocket events
+// are detected by the connection ID in their request context, VPC Lattice events by their
+// raw_path field (V1) or the target group in their request context (V2), and other proxy
+// events like SwitchableRequest does. Record based events (SQS, SNS, S3) are detected by the event
+// source of their first record, and EventBridge events by their detail-type and source
+// fields. Payloads that match none of them return EventSourceUnknown.
+func DetectEventSource(b []byte) EventSource {
+	if isWebsocketEvent(b) {
+		return EventSourceWebsocket
+	}
+	if source := detectLatticeEvent(b); source != EventSourceUnknown {
+		return source
+	}
+
+	switch detectProxyEvent(b) {
+	case proxyEventALB:
+		return EventSourceALB
+	case proxyEventV1:
+		return EventSourceAPIGatewayV1
+	case proxyEventV2:
+		return EventSourceAPIGatewayV2
+	}
+
+	delta := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &delta); err != nil {
+		return EventSourceUnknown
+	}
+
+	if raw, ok := delta["Records"]; ok {
+		return detectRecordSource(raw)
+	}
+
+	_, detailTypeTest := delta["detail-type"]
+	_, sourceTest := delta["source"]
+	if detailTypeTest && sourceTest {
+		return EventSourceEventBridge
+	}
+	return EventSourceUnknown
+}
+
+// detectRecordSource reads the event source of the first record. SQS and S3 records
+// use eventSource, SNS records use EventSource.
+func detectRecordSource(raw json.RawMessage) EventSource {
+	var records []struct {
+		EventSource    string `json:"eventSource"`
+		EventSourceSNS string `json:"EventSource"`
+	}
+	if err := json.Unmarshal(raw, &records); err != nil || len(records) == 0 {
+		return EventSourceUnknown
+	}
+
+	source := records[0].EventSource
+	if source == "" {
+		source = records[0].EventSourceSNS
+	}
+	switch source {
+	case "aws:sqs":
+		return EventSourceSQS
+	case "aws:sns":
+		return EventSourceSNS
+	case "aws:s3":
+		return EventSourceS3
+	}
+	return EventSourceUnknown
+}
+
+// isWebsocketEvent reports whether the request context of the event carries a
+// WebSocket connection ID and event type.
+func isWebsocketEvent(b []byte) bool {
+	var event struct {
+		RequestContext struct {
+			ConnectionID string `json:"connectionId"`
+			EventType    string `json:"eventType"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return false
+	}
+	return event.RequestContext.ConnectionID != "" && event.RequestContext.EventType != ""
+}
+
+// detectLatticeEvent tells V1 Lattice events, which use snake case fields, from V2
+// events, whose version 2.0 they share with API Gateway v2 events.
+func detectLatticeEvent(b []byte) EventSource {
+	var event struct {
+		RawPath        *string `json:"raw_path"`
+		RequestContext struct {
+			TargetGroupARN string `json:"targetGroupArn"`
+			ServiceARN     string `json:"serviceArn"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return EventSourceUnknown
+	}
+	switch {
+	case event.RawPath != nil:
+		return EventSourceVPCLattice
+	case event.RequestContext.TargetGroupARN != "" || event.RequestContext.ServiceARN != "":
+		return EventSourceVPCLatticeV2
+	}
+	return EventSourceUnknown
+}
@@ -0,0 +1,32 @@
+package core_test
+
+import (
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DetectEventSource", func() {
+	DescribeTable("detects the source from the distinguishing fields",
+		func(payload string, want core.EventSource) {
+			Expect(core.DetectEventSource([]byte(payload))).To(Equal(want))
+		},
+		Entry("ALB", `{"httpMethod":"GET","path":"/","requestContext":{"elb":{"targetGroupArn":"arn"}}}`, core.EventSourceALB),
+		Entry("API Gateway v1", `{"httpMethod":"GET","path":"/","requestContext":{"stage":"prod"}}`, core.EventSourceAPIGatewayV1),
+		Entry("API Gateway v2", `{"version":"2.0","rawPath":"/","rawQueryString":""}`, core.EventSourceAPIGatewayV2),
+		Entry("SQS", `{"Records":[{"messageId":"1","eventSource":"aws:sqs","body":"{}"}]}`, core.EventSourceSQS),
+		Entry("SNS", `{"Records":[{"EventSource":"aws:sns","EventVersion":"1.0","Sns":{"Message":"hi"}}]}`, core.EventSourceSNS),
+		Entry("S3", `{"Records":[{"eventVersion":"2.1","eventSource":"aws:s3","s3":{"bucket":{"name":"b"}}}]}`, core.EventSourceS3),
+		Entry("EventBridge", `{"id":"1","detail-type":"Scheduled Event","source":"aws.events","detail":{}}`, core.EventSourceEventBridge),
+		Entry("WebSocket connect", `{"headers":{"Host":"abc.execute-api"},"requestContext":{"routeKey":"$connect","eventType":"CONNECT","connectionId":"c1"},"isBase64Encoded":false}`, core.EventSourceWebsocket),
+		Entry("WebSocket message", `{"requestContext":{"routeKey":"sendmessage","eventType":"MESSAGE","connectionId":"c1"},"body":"{}"}`, core.EventSourceWebsocket),
+		Entry("VPC Lattice V1", `{"raw_path":"/orders","method":"GET","headers":{"host":"orders"},"is_base64_encoded":false}`, core.EventSourceVPCLattice),
+		Entry("VPC Lattice V2", `{"version":"2.0","path":"/orders","method":"GET","requestContext":{"serviceArn":"arn","targetGroupArn":"arn"}}`, core.EventSourceVPCLatticeV2),
+		Entry("empty records", `{"Records":[]}`, core.EventSourceUnknown),
+		Entry("unknown record source", `{"Records":[{"eventSource":"aws:dynamodb"}]}`, core.EventSourceUnknown),
+		Entry("unrelated object", `{"hello":"world"}`, core.EventSourceUnknown),
+		Entry("not an object", `[1,2,3]`, core.EventSourceUnknown),
+	)
+})
@@ -0,0 +1,346 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// HTTPEvent is the HTTP request carried by a proxy event, as extracted by an EventMapper.
+type HTTPEvent struct {
+	Method string
+
+	// Path is the request path as delivered, optionally followed by the query string.
+	Path string
+
+	// RawQuery is the encoded query string. It takes precedence over a query in Path.
+	RawQuery string
+
+	Header          http.Header
+	Body            string
+	IsBase64Encoded bool
+
+	// Scheme and Host make up the server address of the request URL, unless the
+	// GO_API_HOST variable is set. Scheme defaults to https.
+	Scheme string
+	Host   string
+
+	// RemoteAddr is the IP address of the client. The front ends do not report the
+	// port of the client, so the request gets port 0, as in "192.0.2.1:0", which
+	// keeps the "IP:port" form net/http documents for http.Request.RemoteAddr.
+	RemoteAddr string
+
+	// BasePath is the part of Path in front of the route the front end matched, such
+	// as the base path mapping of a custom domain or the stage of a default endpoint.
+	// Mappers set it when the event tells, it is stripped when the converter derives
+	// base paths, see DeriveBasePath.
+	BasePath string
+}
+
+// EventMapper extracts the HTTP request of the events of one front end, which a
+// RequestConverter turns into an http.Request. Everything the front ends have in
+// common, body decoding, base path stripping, the server address and logging, is
+// left to the converter so every event shape behaves the same way.
+type EventMapper[E any] interface {
+	// MapEvent returns the HTTP request of event. Headers must be canonical, as
+	// produced by http.Header.Add, and the query string encoded.
+	MapEvent(event E) (HTTPEvent, error)
+
+	// AddToContext returns req with the lambda context and the front end specific
+	// values of event added to ctx, for the context accessors of the front end.
+	AddToContext(ctx context.Context, req *http.Request, event E) *http.Request
+}
+
+// RequestConverter converts events into http.Requests with an EventMapper. The
+// RequestAccessors use it with the mappers of this package; custom event shapes
+// are plugged in with their own mapper:
+//
+//	converter := core.NewRequestConverter[MyEvent](myMapper{})
+//	converter.StripBasePath("/orders")
+//	req, err := converter.EventToRequestWithContext(ctx, event)
+type RequestConverter[E any] struct {
+	basePath
+	mapper EventMapper[E]
+}
+
+func NewRequestConverter[E any](mapper EventMapper[E]) *RequestConverter[E] {
+	return &RequestConverter[E]{mapper: mapper}
+}
+
+// EventToRequestWithContext converts an event and context into an http.Request object.
+// Returns the populated http request with the values added by the mapper as part of its context.
+func (c *RequestConverter[E]) EventToRequestWithContext(ctx context.Context, event E) (*http.Request, error) {
+	return eventToRequestWithContext(ctx, &c.basePath, c.mapper, event)
+}
+
+// EventToRequest converts an event into an http.Request object.
+// Returns the populated request maintaining headers
+func (c *RequestConverter[E]) EventToRequest(event E) (*http.Request, error) {
+	return eventToRequest(&c.basePath, c.mapper, event)
+}
+
+func eventToRequestWithContext[E any](ctx context.Context, b *basePath, m EventMapper[E], event E) (*http.Request, error) {
+	httpRequest, err := mapRequest(ctx, b, m, event)
+	if err != nil {
+		return nil, err
+	}
+	// the request context is ctx with the matched base path, if any
+	return m.AddToContext(httpRequest.Context(), httpRequest, event), nil
+}
+
+func eventToRequest[E any](b *basePath, m EventMapper[E], event E) (*http.Request, error) {
+	return mapRequest(context.Background(), b, m, event)
+}
+
+func mapRequest[E any](ctx context.Context, b *basePath, m EventMapper[E], event E) (*http.Request, error) {
+	e, err := m.MapEvent(event)
+	if err != nil {
+		appLog.Info("Could not map event to request", "err", err)
+		return nil, err
+	}
+	return b.newRequest(ctx, e)
+}
+
+// newRequest builds the http.Request of a mapped event. The base path that was
+// stripped from its path is added to ctx, see GetBasePathFromContext.
+func (b *basePath) newRequest(ctx context.Context, e HTTPEvent) (*http.Request, error) {
+	// the body is read straight from the event unless it has to be decoded
+	var body io.Reader = strings.NewReader(e.Body)
+	if e.IsBase64Encoded {
+		decodedBody, err := decodeBase64(e.Body)
+		if err != nil {
+			appLog.Info("Error in base64 decode", "err", err)
+			return nil, err
+		}
+		body = bytes.NewReader(decodedBody)
+	}
+
+	path, query := e.Path, e.RawQuery
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		if query == "" {
+			query = path[i+1:]
+		}
+		path = path[:i]
+	}
+	path, matched := b.strip(path, e.BasePath)
+	if matched != "" {
+		ctx = context.WithValue(ctx, basePathKey{}, matched)
+	}
+
+	scheme := e.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	httpRequest, err := http.NewRequestWithContext(
+		ctx,
+		strings.ToUpper(e.Method),
+		requestURL(scheme, e.Host, path, query),
+		body,
+	)
+
+	if err != nil {
+		appLog.Info("Could not convert request to http.Request", "method", e.Method, "path", e.Path, "err", err)
+		return nil, err
+	}
+
+	if e.Header != nil {
+		httpRequest.Header = e.Header
+	}
+	if e.RemoteAddr != "" {
+		httpRequest.RemoteAddr = net.JoinHostPort(e.RemoteAddr, "0")
+	}
+	httpRequest.RequestURI = httpRequest.URL.RequestURI()
+
+	return httpRequest, nil
+}
+
+// remoteIP returns the IP address of the RemoteAddr of a converted request.
+func remoteIP(remoteAddr string) string {
+	if ip, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return ip
+	}
+	return remoteAddr
+}
+
+// requestURL joins the parts of the request URL in a single allocation.
+func requestURL(scheme, host, path, query string) string {
+	var sb strings.Builder
+	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
+		sb.Grow(len(customAddress) + len(path) + len(query) + 1)
+		sb.WriteString(customAddress)
+	} else {
+		sb.Grow(len(scheme) + len("://") + len(host) + len(path) + len(query) + 1)
+		sb.WriteString(scheme)
+		sb.WriteString("://")
+		sb.WriteString(host)
+	}
+	sb.WriteString(path)
+	if query != "" {
+		sb.WriteByte('?')
+		sb.WriteString(query)
+	}
+	return sb.String()
+}
+
+// basePath holds the base paths a RequestAccessor removes from request paths.
+type basePath struct {
+	// basePaths are normalized and sorted longest first, so the most specific wins
+	basePaths []string
+	derive    bool
+}
+
+// StripBasePath instructs the RequestAccessor object that the given base
+// path should be removed from the request path before sending it to the
+// framework for routing. This is used when API Gateway is configured with
+// base path mappings in custom domain names. It replaces the base paths set
+// before; an empty base path turns stripping off.
+//
+// The base path only matches whole path segments: "/pay" is removed from
+// "/pay/slips" and "/pay", but no longer from "/payroll", which is passed
+// to the framework as is.
+func (b *basePath) StripBasePath(basePath string) string {
+	newBasePath := normalizeBasePath(basePath)
+	b.basePaths = nil
+	if newBasePath != "" {
+		b.basePaths = []string{newBasePath}
+	}
+	return newBasePath
+}
+
+// StripBasePaths sets several base paths to be removed from request paths, for
+// custom domains with one base path mapping per API, such as /attendance and
+// /payroll, in front of the same function. The longest base path the request
+// path starts with is removed. Like StripBasePath it matches whole path
+// segments only. It replaces the base paths set before.
+func (b *basePath) StripBasePaths(basePaths ...string) {
+	// a fresh slice, as accessors copied before share the backing array of the old one
+	paths := make([]string, 0, len(basePaths))
+	for _, p := range basePaths {
+		if p = normalizeBasePath(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) > len(paths[j])
+	})
+	b.basePaths = paths
+}
+
+// DeriveBasePath enables or disables removing the base path the front end reports
+// in the event, for requests that match none of the configured base paths. API
+// Gateway events tell it from the route or resource that matched and the stage, so
+// the base path mapping of a custom domain in front of a REST API resource or an HTTP
+// API route, and the stage of the default endpoint of HTTP APIs, is removed without
+// configuration. HTTP API requests matched by the $default route carry no route to
+// tell the mapping from, so only a stage other than $default is removed for them;
+// configure the mapping keys of such APIs with StripBasePaths. Other front ends
+// report none.
+func (b *basePath) DeriveBasePath(enabled bool) {
+	b.derive = enabled
+}
+
+// strip removes the base path from path and makes sure the result starts with a slash.
+// It returns the base path it removed, if any.
+func (b *basePath) strip(path, derived string) (string, string) {
+	matched := ""
+	for _, p := range b.basePaths {
+		if hasPathPrefix(path, p) {
+			matched = p
+			break
+		}
+	}
+	if matched == "" && b.derive {
+		if derived = normalizeBasePath(derived); derived != "" && hasPathPrefix(path, derived) {
+			matched = derived
+		}
+	}
+	path = path[len(matched):]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path, matched
+}
+
+// normalizeBasePath returns basePath with a leading and without a trailing slash,
+// or "" for empty base paths and the root.
+func normalizeBasePath(basePath string) string {
+	if strings.Trim(basePath, " ") == "" {
+		return ""
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+	if len(basePath) <= 1 {
+		return ""
+	}
+	return basePath
+}
+
+// routeBasePath returns the part of path in front of route, a resource path whose
+// {param} and {param+} segments are filled in from params. It returns false if path
+// does not end with the route. path may be percent-encoded, as the RawPath of HTTP
+// API events is, while the parameter values are always decoded: every route segment
+// is compared with as many path segments, decoded.
+func routeBasePath(path, route string, params map[string]string) (string, bool) {
+	if !strings.HasPrefix(route, "/") {
+		return "", false
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	if route == "/" {
+		return strings.TrimSuffix(path, "/"), true
+	}
+
+	// match the segments from the end, the greedy {proxy+} segment is always the last
+	for route != "" {
+		i := strings.LastIndexByte(route, '/')
+		segment := route[i+1:]
+		route = route[:i]
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			value, ok := params[strings.TrimSuffix(segment[1:len(segment)-1], "+")]
+			if !ok {
+				return "", false
+			}
+			segment = value
+		}
+
+		// the value of a greedy parameter spans several path segments
+		n := len(path)
+		for k := strings.Count(segment, "/"); k >= 0; k-- {
+			if n = strings.LastIndexByte(path[:n], '/'); n < 0 {
+				return "", false
+			}
+		}
+		if tail := path[n+1:]; tail != segment {
+			if decoded, err := url.PathUnescape(tail); err != nil || decoded != segment {
+				return "", false
+			}
+		}
+		path = path[:n]
+	}
+	return path, true
+}
+
+// hasPathPrefix reports whether path starts with the segments of prefix, so /pay
+// is a prefix of /pay/slips but not of /payroll.
+func hasPathPrefix(path, prefix string) bool {
+	return strings.HasPrefix(path, prefix) && (len(path) == len(prefix) || path[len(prefix)] == '/')
+}
+
+type basePathKey struct{}
+
+// GetBasePathFromContext returns the base path that was removed from the path of the
+// request, which tells handlers the base path mapping a request came through.
+func GetBasePathFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(basePathKey{}).(string)
+	return v, ok
+}
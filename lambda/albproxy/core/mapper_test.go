@@ -0,0 +1,345 @@
+package core_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// httpFixture is the front end independent request every mapper case builds its event from.
+type httpFixture struct {
+	method   string
+	path     string
+	query    map[string]string
+	header   map[string]string
+	body     string
+	base64   bool
+	host     string
+	sourceIP string
+}
+
+// basePathStripper configures the base paths of a converter.
+type basePathStripper interface {
+	StripBasePath(basePath string) string
+	StripBasePaths(basePaths ...string)
+}
+
+// mapperCase converts a fixture with a converter built on the mapper under test.
+type mapperCase struct {
+	name    string
+	convert func(ctx context.Context, strip func(basePathStripper), f httpFixture) (*http.Request, error)
+
+	// synthetic cases map events that are not HTTP requests, such as SQS messages.
+	// Their requests have no client address and a host of their own, their bodies
+	// are never base64 encoded, and the fixture headers arrive with headerPrefix.
+	synthetic    bool
+	headerPrefix string
+}
+
+func newMapperCase[E any](name string, mapper core.EventMapper[E], event func(f httpFixture) E) mapperCase {
+	return mapperCase{
+		name: name,
+		convert: func(ctx context.Context, strip func(basePathStripper), f httpFixture) (*http.Request, error) {
+			converter := core.NewRequestConverter(mapper)
+			if strip != nil {
+				strip(converter)
+			}
+			return converter.EventToRequestWithContext(ctx, event(f))
+		},
+	}
+}
+
+// syntheticMapperCase is a mapperCase of events that are not HTTP requests.
+func syntheticMapperCase[E any](name, headerPrefix string, mapper core.EventMapper[E], event func(f httpFixture) E) mapperCase {
+	mc := newMapperCase(name, mapper, event)
+	mc.synthetic, mc.headerPrefix = true, headerPrefix
+	return mc
+}
+
+// customEvent stands for an event shape defined outside this package.
+type customEvent struct {
+	Verb    string
+	URL     string
+	Headers map[string]string
+	Payload string
+	Encoded bool
+	Caller  string
+}
+
+type customMapper struct{}
+
+func (customMapper) MapEvent(e customEvent) (core.HTTPEvent, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return core.HTTPEvent{}, err
+	}
+	header := make(http.Header)
+	for k, v := range e.Headers {
+		header.Add(k, v)
+	}
+	return core.HTTPEvent{
+		Method:          e.Verb,
+		Path:            u.EscapedPath(),
+		RawQuery:        u.RawQuery,
+		Header:          header,
+		Body:            e.Payload,
+		IsBase64Encoded: e.Encoded,
+		Scheme:          u.Scheme,
+		Host:            u.Host,
+		RemoteAddr:      e.Caller,
+	}, nil
+}
+
+func (customMapper) AddToContext(ctx context.Context, req *http.Request, e customEvent) *http.Request {
+	return req.WithContext(ctx)
+}
+
+func encodedQuery(query map[string]string) string {
+	values := url.Values{}
+	for k, v := range query {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+func multiValues(values map[string]string) map[string][]string {
+	multi := make(map[string][]string, len(values))
+	for k, v := range values {
+		multi[k] = []string{v}
+	}
+	return multi
+}
+
+var mapperCases = []mapperCase{
+	newMapperCase[events.APIGatewayProxyRequest]("API Gateway v1", core.MapperAPIGatewayV1{}, func(f httpFixture) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			HTTPMethod:                      f.method,
+			Path:                            f.path,
+			MultiValueQueryStringParameters: multiValues(f.query),
+			MultiValueHeaders:               multiValues(f.header),
+			Body:                            f.body,
+			IsBase64Encoded:                 f.base64,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				DomainName: f.host,
+				Identity:   events.APIGatewayRequestIdentity{SourceIP: f.sourceIP},
+			},
+		}
+	}),
+	newMapperCase[events.APIGatewayV2HTTPRequest]("API Gateway v2", core.MapperAPIGatewayV2{}, func(f httpFixture) events.APIGatewayV2HTTPRequest {
+		return events.APIGatewayV2HTTPRequest{
+			RawPath:         f.path,
+			RawQueryString:  encodedQuery(f.query),
+			Headers:         f.header,
+			Body:            f.body,
+			IsBase64Encoded: f.base64,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				DomainName: f.host,
+				HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: f.method, SourceIP: f.sourceIP},
+			},
+		}
+	}),
+	newMapperCase[events.ALBTargetGroupRequest]("ALB", core.MapperALB{}, func(f httpFixture) events.ALBTargetGroupRequest {
+		query := make(map[string][]string, len(f.query))
+		for k, v := range f.query {
+			query[url.QueryEscape(k)] = []string{url.QueryEscape(v)}
+		}
+		header := multiValues(f.header)
+		header["host"] = []string{f.host}
+		header["x-forwarded-for"] = []string{f.sourceIP}
+		header["x-forwarded-proto"] = []string{"https"}
+		return events.ALBTargetGroupRequest{
+			HTTPMethod:                      f.method,
+			Path:                            f.path,
+			MultiValueQueryStringParameters: query,
+			MultiValueHeaders:               header,
+			Body:                            f.body,
+			IsBase64Encoded:                 f.base64,
+			RequestContext:                  events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+		}
+	}),
+	newMapperCase[core.VPCLatticeRequest]("VPC Lattice V1", core.MapperLattice{}, func(f httpFixture) core.VPCLatticeRequest {
+		header := map[string]string{"host": f.host, "x-forwarded-for": f.sourceIP}
+		for k, v := range f.header {
+			header[k] = v
+		}
+		return core.VPCLatticeRequest{
+			Method:                f.method,
+			RawPath:               f.path,
+			Headers:               header,
+			QueryStringParameters: f.query,
+			Body:                  f.body,
+			IsBase64Encoded:       f.base64,
+		}
+	}),
+	newMapperCase[core.VPCLatticeRequestV2]("VPC Lattice V2", core.MapperLatticeV2{}, func(f httpFixture) core.VPCLatticeRequestV2 {
+		header := multiValues(f.header)
+		header["host"] = []string{f.host}
+		header["x-forwarded-for"] = []string{f.sourceIP}
+		return core.VPCLatticeRequestV2{
+			Version:               "2.0",
+			Method:                f.method,
+			Path:                  f.path,
+			Headers:               header,
+			QueryStringParameters: multiValues(f.query),
+			Body:                  f.body,
+			IsBase64Encoded:       f.base64,
+		}
+	}),
+	newMapperCase[events.APIGatewayWebsocketProxyRequest]("WebSocket", core.MapperWebsocket{}, func(f httpFixture) events.APIGatewayWebsocketProxyRequest {
+		return events.APIGatewayWebsocketProxyRequest{
+			MultiValueQueryStringParameters: multiValues(f.query),
+			MultiValueHeaders:               multiValues(f.header),
+			Body:                            f.body,
+			IsBase64Encoded:                 f.base64,
+			RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+				RouteKey:     strings.TrimPrefix(f.path, "/"),
+				ConnectionID: "abc=",
+				DomainName:   f.host,
+				Identity:     events.APIGatewayRequestIdentity{SourceIP: f.sourceIP},
+			},
+		}
+	}),
+	syntheticMapperCase[events.SQSMessage]("SQS", core.SQSAttributeHeaderPrefix, core.MapperSQS{RouteAttribute: "route"}, func(f httpFixture) events.SQSMessage {
+		route := f.path
+		if len(f.query) > 0 {
+			route += "?" + encodedQuery(f.query)
+		}
+		attributes := map[string]events.SQSMessageAttribute{"route": {StringValue: &route, DataType: "String"}}
+		for k, v := range f.header {
+			attributes[k] = events.SQSMessageAttribute{StringValue: &v, DataType: "String"}
+		}
+		return events.SQSMessage{
+			MessageId:         "m1",
+			Body:              f.body,
+			MessageAttributes: attributes,
+			EventSourceARN:    "arn:aws:sqs:ap-south-1:000000000000:orders",
+			AWSRegion:         "ap-south-1",
+		}
+	}),
+	newMapperCase[customEvent]("custom event", customMapper{}, func(f httpFixture) customEvent {
+		u := url.URL{Scheme: "https", Host: f.host, Path: f.path, RawQuery: encodedQuery(f.query)}
+		return customEvent{
+			Verb:    f.method,
+			URL:     u.String(),
+			Headers: f.header,
+			Payload: f.body,
+			Encoded: f.base64,
+			Caller:  f.sourceIP,
+		}
+	}),
+}
+
+type mapperCtxKey struct{}
+
+var _ = Describe("EventMapper", func() {
+	fixture := func() httpFixture {
+		return httpFixture{
+			method:   "post",
+			path:     "/api/orders/42",
+			query:    map[string]string{"q": "a b&c", "page": "2"},
+			header:   map[string]string{"content-type": "application/json", "x-request-id": "r1"},
+			body:     `{"id":42}`,
+			host:     "orders.example.com",
+			sourceIP: "192.0.2.1",
+		}
+	}
+
+	for _, mc := range mapperCases {
+		Describe(mc.name, func() {
+			It("converts method, URL, headers, body and client address", func() {
+				req, err := mc.convert(context.Background(), nil, fixture())
+				Expect(err).To(BeNil())
+				Expect(req.Method).To(Equal(http.MethodPost))
+				Expect(req.URL.Scheme).To(Equal("https"))
+				Expect(req.URL.Path).To(Equal("/api/orders/42"))
+				Expect(req.URL.Query().Get("q")).To(Equal("a b&c"))
+				Expect(req.URL.Query().Get("page")).To(Equal("2"))
+				Expect(req.RequestURI).To(Equal(req.URL.RequestURI()))
+				Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(req.Header.Get(mc.headerPrefix + "X-Request-Id")).To(Equal("r1"))
+				if !mc.synthetic {
+					Expect(req.Host).To(Equal("orders.example.com"))
+					Expect(req.RemoteAddr).To(Equal("192.0.2.1:0"))
+					ip, _, err := net.SplitHostPort(req.RemoteAddr)
+					Expect(err).To(BeNil())
+					Expect(ip).To(Equal("192.0.2.1"))
+				}
+				body, _ := io.ReadAll(req.Body)
+				Expect(string(body)).To(Equal(`{"id":42}`))
+			})
+
+			// the events of synthetic cases have no base64 bodies
+			if !mc.synthetic {
+				It("decodes base64 bodies", func() {
+					f := fixture()
+					f.body, f.base64 = base64.StdEncoding.EncodeToString([]byte{0xff, 0x00}), true
+					req, err := mc.convert(context.Background(), nil, f)
+					Expect(err).To(BeNil())
+					body, _ := io.ReadAll(req.Body)
+					Expect(body).To(Equal([]byte{0xff, 0x00}))
+				})
+
+				It("rejects invalid base64 bodies", func() {
+					f := fixture()
+					f.body, f.base64 = "%%%", true
+					_, err := mc.convert(context.Background(), nil, f)
+					Expect(err).ToNot(BeNil())
+				})
+			}
+
+			It("strips the base path", func() {
+				req, err := mc.convert(context.Background(), func(b basePathStripper) { b.StripBasePath("api/") }, fixture())
+				Expect(err).To(BeNil())
+				Expect(req.URL.Path).To(Equal("/orders/42"))
+				basePath, ok := core.GetBasePathFromContext(req.Context())
+				Expect(ok).To(BeTrue())
+				Expect(basePath).To(Equal("/api"))
+			})
+
+			It("strips the longest matching of several base paths", func() {
+				strip := func(b basePathStripper) { b.StripBasePaths("/ap", "/api", "/api/orders/", "/payroll") }
+				req, err := mc.convert(context.Background(), strip, fixture())
+				Expect(err).To(BeNil())
+				Expect(req.URL.Path).To(Equal("/42"))
+				basePath, _ := core.GetBasePathFromContext(req.Context())
+				Expect(basePath).To(Equal("/api/orders"))
+
+				f := fixture()
+				f.path = "/payroll-archive/7"
+				req, err = mc.convert(context.Background(), strip, f)
+				Expect(err).To(BeNil())
+				Expect(req.URL.Path).To(Equal("/payroll-archive/7"))
+				_, ok := core.GetBasePathFromContext(req.Context())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("uses the custom host of GO_API_HOST", func() {
+				os.Setenv(core.CustomHostVariable, "http://localhost:8080")
+				defer os.Unsetenv(core.CustomHostVariable)
+				req, err := mc.convert(context.Background(), nil, fixture())
+				Expect(err).To(BeNil())
+				Expect(req.URL.String()).To(HavePrefix("http://localhost:8080/api/orders/42?"))
+			})
+
+			It("keeps the values of the invocation context", func() {
+				ctx := context.WithValue(context.Background(), mapperCtxKey{}, "v")
+				req, err := mc.convert(ctx, nil, fixture())
+				Expect(err).To(BeNil())
+				Expect(req.Context().Value(mapperCtxKey{})).To(Equal("v"))
+			})
+
+		})
+	}
+})
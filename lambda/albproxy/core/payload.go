@@ -0,0 +1,250 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxResponseSizeALB is the largest response payload ALB accepts from a Lambda target.
+	MaxResponseSizeALB = 1 << 20
+
+	// MaxResponseSizeAPIGateway is the largest synchronous Lambda response payload,
+	// which bounds API Gateway and Function URL responses.
+	MaxResponseSizeAPIGateway = 6 << 20
+
+	// MaxResponseSizeLattice is the largest response payload of VPC Lattice Lambda
+	// targets, which is the synchronous Lambda limit as well.
+	MaxResponseSizeLattice = MaxResponseSizeAPIGateway
+
+	// DefaultMinCompressSize is the smallest body that is compressed.
+	DefaultMinCompressSize = 1024
+
+	contentEncodingHeaderKey = "Content-Encoding"
+)
+
+// PayloadOptions control how a proxy response writer encodes the body and
+// enforces the response payload limit of the front end.
+type PayloadOptions struct {
+	// AcceptEncoding is the Accept-Encoding header of the request. The body is
+	// compressed with gzip or deflate when it allows it.
+	AcceptEncoding string
+
+	// DisableCompression turns compression off regardless of AcceptEncoding.
+	DisableCompression bool
+
+	// MinCompressSize is the smallest body that is compressed.
+	// Zero means DefaultMinCompressSize.
+	MinCompressSize int
+
+	// MaxSize is the largest serialized response accepted by the front end.
+	// Zero means the default limit of the writer.
+	MaxSize int
+
+	// BinaryMediaTypes lists the content types returned base64 encoded, for
+	// example "image/*" or "application/pdf". When empty, bodies that are not
+	// valid UTF-8 are treated as binary.
+	BinaryMediaTypes []string
+
+	// DisableContentSniffing leaves the Content-Type header unset when the
+	// handler did not set one, instead of detecting it from the body.
+	DisableContentSniffing bool
+}
+
+func (o PayloadOptions) maxSize(def int) int {
+	if o.MaxSize > 0 {
+		return o.MaxSize
+	}
+	return def
+}
+
+// encodeBody detects the content type if needed, compresses the body when the
+// client accepts it and returns the response body together with whether it is
+// base64 encoded.
+func (o PayloadOptions) encodeBody(headers http.Header, bb []byte) (string, bool) {
+	// if the content type header is not set we try to detect one from the
+	// complete body. If the content type cannot be detected it is set to
+	// "application/octet-stream" by the DetectContentType method
+	if !o.DisableContentSniffing && len(bb) > 0 && headers.Get(contentTypeHeaderKey) == "" {
+		headers.Set(contentTypeHeaderKey, http.DetectContentType(bb))
+	}
+
+	if compressed, ok := o.compress(headers, bb); ok {
+		defer putBuffer(compressed)
+		return encodeBase64(compressed.Bytes()), true
+	}
+
+	if o.isBinary(headers, bb) {
+		return encodeBase64(bb), true
+	}
+	return string(bb), false
+}
+
+// isBinary reports whether the body must be base64 encoded. Encoded bodies and
+// configured binary media types always are; invalid UTF-8 cannot be carried in
+// a JSON string either way.
+func (o PayloadOptions) isBinary(headers http.Header, bb []byte) bool {
+	if headers.Get(contentEncodingHeaderKey) != "" && headers.Get(contentEncodingHeaderKey) != "identity" {
+		return true
+	}
+	if len(o.BinaryMediaTypes) > 0 && MatchMediaType(headers.Get(contentTypeHeaderKey), o.BinaryMediaTypes) {
+		return true
+	}
+	return !utf8.Valid(bb)
+}
+
+// MatchMediaType reports whether the media type of contentType matches one of
+// patterns. Patterns may use wildcards such as "image/*" or "*/*".
+func MatchMediaType(contentType string, patterns []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		pt, ps, _ := strings.Cut(pattern, "/")
+		if (pt == "*" || pt == typ) && (ps == "*" || ps == subtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// compress encodes bb with the preferred encoding of the client and updates the
+// headers accordingly. It returns false if the body is left as is. The buffer
+// with the compressed body is pooled and must be released with putBuffer.
+func (o PayloadOptions) compress(headers http.Header, bb []byte) (*bytes.Buffer, bool) {
+	minSize := o.MinCompressSize
+	if minSize <= 0 {
+		minSize = DefaultMinCompressSize
+	}
+	if o.DisableCompression || len(bb) < minSize || headers.Get(contentEncodingHeaderKey) != "" {
+		return nil, false
+	}
+	if !isCompressible(headers.Get(contentTypeHeaderKey)) {
+		return nil, false
+	}
+
+	encoding := negotiateEncoding(o.AcceptEncoding)
+	if encoding == "" {
+		return nil, false
+	}
+
+	buf := getBuffer()
+	var zw interface {
+		io.WriteCloser
+		Reset(io.Writer)
+	}
+	if encoding == "gzip" {
+		gw := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(gw)
+		zw = gw
+	} else {
+		zlw := zlibWriterPool.Get().(*zlib.Writer)
+		defer zlibWriterPool.Put(zlw)
+		zw = zlw
+	}
+	zw.Reset(buf)
+	if _, err := zw.Write(bb); err != nil {
+		appLog.Error("Could not compress response body", "encoding", encoding, "err", err)
+		putBuffer(buf)
+		return nil, false
+	}
+	if err := zw.Close(); err != nil {
+		appLog.Error("Could not compress response body", "encoding", encoding, "err", err)
+		putBuffer(buf)
+		return nil, false
+	}
+	// base64 adds a third, so small gains are not worth it
+	if base64.StdEncoding.EncodedLen(buf.Len()) >= len(bb) {
+		putBuffer(buf)
+		return nil, false
+	}
+
+	headers.Set(contentEncodingHeaderKey, encoding)
+	headers.Del("Content-Length")
+	headers.Add("Vary", "Accept-Encoding")
+	return buf, true
+}
+
+// negotiateEncoding returns "gzip", "deflate" or "" for the Accept-Encoding header,
+// honoring q=0 exclusions and the * wildcard.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	best, bestQ := "", 0.0
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q
+	}
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// isCompressible reports whether a content type is text-like. Images, video and
+// archives are already compressed.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml", "application/x-ndjson":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// exceedsPayloadLimit reports whether resp serializes to more than limit bytes.
+// JSON escaping can grow a body up to six times, so responses that cannot
+// reach the limit are not serialized.
+func exceedsPayloadLimit(resp interface{}, bodyLen int, headers http.Header, limit int) (bool, int) {
+	estimate := bodyLen * 6
+	for k, values := range headers {
+		for _, v := range values {
+			estimate += (len(k) + len(v)) * 6
+		}
+	}
+	if estimate+1024 < limit {
+		return false, 0
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return false, 0
+	}
+	return len(b) > limit, len(b)
+}
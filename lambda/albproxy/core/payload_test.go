@@ -0,0 +1,170 @@
+package core_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PayloadOptions", func() {
+	body := strings.Repeat(`{"name":"attendance","present":true},`, 100)
+
+	It("gzips text bodies when the client accepts it", func() {
+		w := core.NewProxyResponseWriterALB()
+		w.SetPayloadOptions(core.PayloadOptions{AcceptEncoding: "deflate;q=0.5, gzip"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		Expect(resp.MultiValueHeaders["Content-Encoding"]).To(Equal([]string{"gzip"}))
+		Expect(resp.MultiValueHeaders["Vary"]).To(Equal([]string{"Accept-Encoding"}))
+
+		compressed, err := base64.StdEncoding.DecodeString(resp.Body)
+		Expect(err).To(BeNil())
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		Expect(err).To(BeNil())
+		plain, err := io.ReadAll(zr)
+		Expect(err).To(BeNil())
+		Expect(string(plain)).To(Equal(body))
+	})
+
+	It("sends deflate responses in the zlib format", func() {
+		w := core.NewProxyResponseWriterV2()
+		w.SetPayloadOptions(core.PayloadOptions{AcceptEncoding: "gzip;q=0.5, deflate"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		Expect(resp.Headers["Content-Encoding"]).To(Equal("deflate"))
+
+		compressed, err := base64.StdEncoding.DecodeString(resp.Body)
+		Expect(err).To(BeNil())
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		Expect(err).To(BeNil())
+		plain, err := io.ReadAll(zr)
+		Expect(err).To(BeNil())
+		Expect(string(plain)).To(Equal(body))
+	})
+
+	It("leaves the body alone when compression is not accepted", func() {
+		w := core.NewProxyResponseWriter()
+		w.SetPayloadOptions(core.PayloadOptions{AcceptEncoding: "gzip;q=0, identity"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeFalse())
+		Expect(resp.Body).To(Equal(body))
+		Expect(resp.MultiValueHeaders).ToNot(HaveKey("Content-Encoding"))
+	})
+
+	It("returns a JSON 413 stating the limit when the response exceeds the payload limit", func() {
+		w := core.NewProxyResponseWriterV2()
+		w.SetPayloadOptions(core.PayloadOptions{MaxSize: 1024})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(resp.Headers["Content-Type"]).To(Equal("application/json"))
+		Expect(resp.Body).To(MatchRegexp(`^\{"Val":"Response of \d+ bytes exceeds the payload limit of 1024 bytes","Err":null,"Status":413\}$`))
+	})
+
+	It("returns a 413 from the ALB and REST writers too", func() {
+		alb := core.NewProxyResponseWriterALB()
+		alb.SetPayloadOptions(core.PayloadOptions{MaxSize: 1024})
+		alb.Write([]byte(body))
+		albResp, err := alb.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(albResp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(albResp.StatusDescription).To(Equal("Request Entity Too Large"))
+		Expect(albResp.Body).To(ContainSubstring("exceeds the payload limit of 1024 bytes"))
+
+		rest := core.NewProxyResponseWriter()
+		rest.SetPayloadOptions(core.PayloadOptions{MaxSize: 1024})
+		rest.Write([]byte(body))
+		restResp, err := rest.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(restResp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+	})
+
+	It("fits a large body under the limit by compressing it", func() {
+		w := core.NewProxyResponseWriterALB()
+		w.SetPayloadOptions(core.PayloadOptions{AcceptEncoding: "gzip", MaxSize: 2048})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.MultiValueHeaders["Content-Encoding"]).To(Equal([]string{"gzip"}))
+	})
+})
+
+var _ = Describe("Binary media types", func() {
+	It("base64 encodes configured media types even when the body is valid UTF-8", func() {
+		w := core.NewProxyResponseWriterALB()
+		w.SetPayloadOptions(core.PayloadOptions{BinaryMediaTypes: []string{"image/*", "application/pdf"}})
+		w.Header().Set("Content-Type", "image/x-portable-graymap")
+		w.Write([]byte("P2 2 1 255 0 0"))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		Expect(resp.Body).To(Equal(base64.StdEncoding.EncodeToString([]byte("P2 2 1 255 0 0"))))
+	})
+
+	It("keeps other media types as text", func() {
+		w := core.NewProxyResponseWriter()
+		w.SetPayloadOptions(core.PayloadOptions{BinaryMediaTypes: []string{"image/*"}})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[1,2]`))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeFalse())
+		Expect(resp.Body).To(Equal(`[1,2]`))
+	})
+
+	It("sniffs the content type from the complete body", func() {
+		w := core.NewProxyResponseWriterV2()
+		w.Write([]byte(`  `))
+		w.Write([]byte(`<html><body>hello</body></html>`))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.Headers["Content-Type"]).To(Equal("text/html; charset=utf-8"))
+	})
+
+	It("does not sniff when sniffing is disabled", func() {
+		w := core.NewProxyResponseWriterV2()
+		w.SetPayloadOptions(core.PayloadOptions{DisableContentSniffing: true})
+		w.Write([]byte(`<html></html>`))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.Headers).ToNot(HaveKey("Content-Type"))
+	})
+
+	It("matches wildcard media types", func() {
+		Expect(core.MatchMediaType("image/png", []string{"image/*"})).To(BeTrue())
+		Expect(core.MatchMediaType("Application/PDF; name=a.pdf", []string{"application/pdf"})).To(BeTrue())
+		Expect(core.MatchMediaType("text/plain", []string{"*/*"})).To(BeTrue())
+		Expect(core.MatchMediaType("text/plain", []string{"image/*"})).To(BeFalse())
+	})
+})
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"sync"
+)
+
+// maxPooledBufferSize bounds the buffers kept for reuse, so that one large
+// response does not pin its memory for the life of the execution environment.
+const maxPooledBufferSize = 256 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// The compressors hold several hundred kilobytes of state each, which would
+// otherwise be allocated for every compressed response.
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() interface{} { return gzip.NewWriter(nil) },
+	}
+	// Content-Encoding: deflate is the zlib format, not raw deflate (RFC 9110)
+	zlibWriterPool = sync.Pool{
+		New: func() interface{} {
+			zw, _ := zlib.NewWriterLevel(nil, zlib.DefaultCompression)
+			return zw
+		},
+	}
+)
+
+// encodeBase64 returns bb base64 encoded, using a pooled buffer for the encoding
+// so that the string is the only allocation.
+func encodeBase64(bb []byte) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	n := base64.StdEncoding.EncodedLen(len(bb))
+	buf.Grow(n)
+	dst := buf.AvailableBuffer()[:n]
+	base64.StdEncoding.Encode(dst, bb)
+	return string(dst)
+}
+
+// decodeBase64 decodes s into a new slice. The encoded string is copied into a
+// pooled buffer, as the decoder only reads byte slices.
+func decodeBase64(s string) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString(s)
+	dst := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+	n, err := base64.StdEncoding.Decode(dst, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return dst[:n], nil
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// buildQuery rebuilds a query string from the query parameters of an event.
+// Multi value parameters take precedence over single value ones, as both are
+// present in events of front ends with multi value support enabled.
+//
+// Events carry parameters in JSON objects, so the order of distinct keys the
+// client sent is lost: a=2&b=1 reaches the handler for b=1&a=2. This applies to
+// API Gateway v1, WebSocket and VPC Lattice events and to single and multi value
+// ALB events alike; only the raw query string of API Gateway v2 and Function URL
+// events keeps it. Keys are sorted to keep the result deterministic, while the
+// order of repeated values of a key is preserved.
+//
+// When escape is false the keys and values are used verbatim. ALB forwards
+// them exactly as they appeared in the URL, already percent-encoded, and
+// escaping them again would double encode values such as %20.
+func buildQuery(multi map[string][]string, single map[string]string, escape bool) string {
+	enc := func(s string) string { return s }
+	if escape {
+		enc = url.QueryEscape
+	}
+
+	size := 0
+	for k, values := range multi {
+		for _, v := range values {
+			size += len(k) + len(v) + 2
+		}
+	}
+	if len(multi) == 0 {
+		for k, v := range single {
+			size += len(k) + len(v) + 2
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(size)
+	add := func(k, v string) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(enc(k))
+		sb.WriteByte('=')
+		sb.WriteString(enc(v))
+	}
+
+	if len(multi) > 0 {
+		for _, k := range sortedKeys(multi) {
+			for _, v := range multi[k] {
+				add(k, v)
+			}
+		}
+	} else {
+		for _, k := range sortedKeys(single) {
+			add(k, single[k])
+		}
+	}
+	return sb.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package core_test
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// queryRebuildCases holds raw query strings as a client would send them, and the query
+// rebuilt from the parameter maps of events such as ALB's. These are not round trips:
+// the rebuilt query sorts the keys, as the maps lose the order the client sent them in.
+var queryRebuildCases = []TableEntry{
+	Entry("simple pairs", "a=1&b=2", "a=1&b=2"),
+	Entry("unsorted keys lose their order", "b=1&a=2", "a=2&b=1"),
+	Entry("encoded space", "q=hello%20world", "q=hello%20world"),
+	Entry("form encoded space", "q=hello+world", "q=hello+world"),
+	Entry("encoded plus", "q=1%2B1", "q=1%2B1"),
+	Entry("repeated keys keep their order", "x=3&x=1&x=2", "x=3&x=1&x=2"),
+	Entry("reserved characters", "email=a%40b.com&path=%2Fa%2Fb%3Fc%3Dd", "email=a%40b.com&path=%2Fa%2Fb%3Fc%3Dd"),
+	Entry("empty value", "empty=&full=1", "empty=&full=1"),
+	Entry("percent sign", "pct=100%25", "pct=100%25"),
+	Entry("utf-8", "mark=%E2%9C%93", "mark=%E2%9C%93"),
+	Entry("encoded ampersand and equals", "expr=a%26b%3Dc", "expr=a%26b%3Dc"),
+}
+
+// splitQuery mimics how ALB fills the query parameters: verbatim, undecoded.
+func splitQuery(raw string) (map[string][]string, map[string]string) {
+	multi := map[string][]string{}
+	single := map[string]string{}
+	for _, part := range strings.Split(raw, "&") {
+		k, v, _ := strings.Cut(part, "=")
+		multi[k] = append(multi[k], v)
+		single[k] = v
+	}
+	return multi, single
+}
+
+// decodeQuery mimics how API Gateway REST APIs fill the query parameters: decoded.
+func decodeQuery(raw string) (map[string][]string, map[string]string) {
+	values, err := url.ParseQuery(raw)
+	Expect(err).To(BeNil())
+	single := map[string]string{}
+	for k, v := range values {
+		single[k] = v[len(v)-1]
+	}
+	return values, single
+}
+
+var _ = Describe("Query string reconstruction", func() {
+	DescribeTable("ALB multi value events keep the query verbatim but for the key order",
+		func(raw, rebuilt string) {
+			multi, _ := splitQuery(raw)
+			accessor := core.RequestAccessorALB{}
+			req, err := accessor.EventToRequest(events.ALBTargetGroupRequest{
+				HTTPMethod:                      "GET",
+				Path:                            "/q",
+				MultiValueHeaders:               map[string][]string{"host": {"example.com"}},
+				MultiValueQueryStringParameters: multi,
+			})
+			Expect(err).To(BeNil())
+			Expect(req.URL.RawQuery).To(Equal(rebuilt))
+			Expect(req.URL.Query()).To(Equal(mustParseQuery(raw)))
+		},
+		queryRebuildCases...,
+	)
+
+	DescribeTable("ALB single value events keep the last value verbatim",
+		func(raw, rebuilt string) {
+			_, single := splitQuery(raw)
+			accessor := core.RequestAccessorALB{}
+			req, err := accessor.EventToRequest(events.ALBTargetGroupRequest{
+				HTTPMethod:            "GET",
+				Path:                  "/q",
+				Headers:               map[string]string{"host": "example.com"},
+				QueryStringParameters: single,
+			})
+			Expect(err).To(BeNil())
+			want := mustParseQuery(raw)
+			for k, v := range req.URL.Query() {
+				Expect(v).To(Equal(want[k][len(want[k])-1:]))
+			}
+		},
+		queryRebuildCases...,
+	)
+
+	DescribeTable("API Gateway v1 events keep the decoded values but not the key order",
+		func(raw, rebuilt string) {
+			multi, single := decodeQuery(raw)
+			accessor := core.RequestAccessor{}
+			req, err := accessor.EventToRequest(events.APIGatewayProxyRequest{
+				HTTPMethod:                      "GET",
+				Path:                            "/q",
+				MultiValueQueryStringParameters: multi,
+				QueryStringParameters:           single,
+			})
+			Expect(err).To(BeNil())
+			Expect(req.URL.Query()).To(Equal(mustParseQuery(raw)))
+
+			// map iteration order must not leak into the rebuilt query
+			again, err := accessor.EventToRequest(events.APIGatewayProxyRequest{
+				HTTPMethod:                      "GET",
+				Path:                            "/q",
+				MultiValueQueryStringParameters: multi,
+			})
+			Expect(err).To(BeNil())
+			Expect(again.URL.RawQuery).To(Equal(req.URL.RawQuery))
+			Expect(queryKeys(req.URL.RawQuery)).To(Equal(queryKeys(rebuilt)))
+		},
+		queryRebuildCases...,
+	)
+
+	DescribeTable("API Gateway v2 events keep the raw query string and its key order",
+		func(raw, rebuilt string) {
+			accessor := core.RequestAccessorV2{}
+			req, err := accessor.EventToRequest(events.APIGatewayV2HTTPRequest{
+				RawPath:        "/q",
+				RawQueryString: raw,
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(req.URL.RawQuery).To(Equal(raw))
+			Expect(req.URL.Query()).To(Equal(mustParseQuery(raw)))
+		},
+		queryRebuildCases...,
+	)
+})
+
+// queryKeys returns the keys of a raw query in order, with a key per value.
+func queryKeys(raw string) []string {
+	var keys []string
+	for _, part := range strings.Split(raw, "&") {
+		k, _, _ := strings.Cut(part, "=")
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func mustParseQuery(raw string) url.Values {
+	values, err := url.ParseQuery(raw)
+	Expect(err).To(BeNil())
+	return values
+}
@@ -3,17 +3,11 @@
 package core
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
-	"net/url"
-	"os"
-	"strings"
+	"net/textproto"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambdacontext"
@@ -27,202 +21,196 @@ const (
 	CustomHostVariable = "GO_API_HOST"
 
 	// APIGwContextHeader is the custom header key used to store the
-	// API Gateway context. To access the Context properties use the
-	// GetAPIGatewayContext method of the RequestAccessor object.
+	// API Gateway context when requested with AddContextHeaders. To access the
+	// Context properties use the GetAPIGatewayContext method of the RequestAccessor object.
 	APIGwContextHeader = "X-GoLambdaProxy-ApiGw-Context"
 
 	// APIGwStageVarsHeader is the custom header key used to store the
-	// API Gateway stage variables. To access the stage variable values
-	// use the GetAPIGatewayStageVars method of the RequestAccessor object.
+	// API Gateway stage variables when requested with AddContextHeaders. To access
+	// the stage variable values use the GetAPIGatewayStageVars method of the RequestAccessor object.
 	APIGwStageVarsHeader = "X-GoLambdaProxy-ApiGw-StageVars"
 )
 
 // RequestAccessor objects give access to custom API Gateway properties
 // in the request.
 type RequestAccessor struct {
-	stripBasePath string
+	basePath
 }
 
-// GetAPIGatewayContext extracts the API Gateway context object from a
-// request's custom header.
+// GetAPIGatewayContext returns the API Gateway context of a request converted by
+// ProxyEventToHTTPRequest or EventToRequestWithContext, or else the context stored
+// in the custom header by AddContextHeaders.
 // Returns a populated events.APIGatewayProxyRequestContext object from
 // the request.
 func (r *RequestAccessor) GetAPIGatewayContext(req *http.Request) (events.APIGatewayProxyRequestContext, error) {
+	if apiGwContext, ok := GetAPIGatewayContextFromContext(req.Context()); ok {
+		return apiGwContext, nil
+	}
 	if req.Header.Get(APIGwContextHeader) == "" {
 		return events.APIGatewayProxyRequestContext{}, errors.New("No context header in request")
 	}
 	context := events.APIGatewayProxyRequestContext{}
 	err := json.Unmarshal([]byte(req.Header.Get(APIGwContextHeader)), &context)
 	if err != nil {
-		log.Println("Error while unmarshalling context")
-		log.Println(err)
+		appLog.Error("Error while unmarshalling context", "err", err)
 		return events.APIGatewayProxyRequestContext{}, err
 	}
 	return context, nil
 }
 
-// GetAPIGatewayStageVars extracts the API Gateway stage variables from a
-// request's custom header.
+// GetAPIGatewayStageVars returns the API Gateway stage variables of a request
+// converted by ProxyEventToHTTPRequest or EventToRequestWithContext, or else the
+// stage variables stored in the custom header by AddContextHeaders.
 // Returns a map[string]string of the stage variables and their values from
 // the request.
 func (r *RequestAccessor) GetAPIGatewayStageVars(req *http.Request) (map[string]string, error) {
+	if stageVars, ok := GetStageVarsFromContext(req.Context()); ok {
+		if stageVars == nil {
+			stageVars = make(map[string]string)
+		}
+		return stageVars, nil
+	}
 	stageVars := make(map[string]string)
 	if req.Header.Get(APIGwStageVarsHeader) == "" {
 		return stageVars, errors.New("No stage vars header in request")
 	}
 	err := json.Unmarshal([]byte(req.Header.Get(APIGwStageVarsHeader)), &stageVars)
 	if err != nil {
-		log.Println("Error while unmarshalling stage variables")
-		log.Println(err)
+		appLog.Error("Error while unmarshalling stage variables", "err", err)
 		return stageVars, err
 	}
 	return stageVars, nil
 }
 
-// StripBasePath instructs the RequestAccessor object that the given base
-// path should be removed from the request path before sending it to the
-// framework for routing. This is used when API Gateway is configured with
-// base path mappings in custom domain names.
-func (r *RequestAccessor) StripBasePath(basePath string) string {
-	if strings.Trim(basePath, " ") == "" {
-		r.stripBasePath = ""
-		return ""
-	}
-
-	newBasePath := basePath
-	if !strings.HasPrefix(newBasePath, "/") {
-		newBasePath = "/" + newBasePath
+// AddContextHeaders serializes the stage variables and API Gateway context of req
+// into the APIGwStageVarsHeader and APIGwContextHeader headers, for handlers that
+// forward the request to services reading them. The conversion does not populate
+// the headers, as marshaling the context on every invocation is costly.
+func (r *RequestAccessor) AddContextHeaders(req *http.Request) error {
+	v, ok := req.Context().Value(ctxKey{}).(requestContext)
+	if !ok {
+		return errors.New("No API Gateway context in request")
 	}
-
-	if strings.HasSuffix(newBasePath, "/") {
-		newBasePath = newBasePath[:len(newBasePath)-1]
-	}
-
-	r.stripBasePath = newBasePath
-
-	return newBasePath
+	return addToHeader(req, v.stageVars, v.gatewayProxyContext)
 }
 
 // ProxyEventToHTTPRequest converts an API Gateway proxy event into a http.Request object.
-// Returns the populated http request with additional two custom headers for the stage variables and API Gateway context.
+// Returns the populated http request with the stage variables and API Gateway context as part of its context.
 // To access these properties use the GetAPIGatewayStageVars and GetAPIGatewayContext method of the RequestAccessor object.
 func (r *RequestAccessor) ProxyEventToHTTPRequest(req events.APIGatewayProxyRequest) (*http.Request, error) {
 	httpRequest, err := r.EventToRequest(req)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-	return addToHeader(httpRequest, req)
+	return addToContext(httpRequest.Context(), httpRequest, req), nil
 }
 
 // EventToRequestWithContext converts an API Gateway proxy event and context into an http.Request object.
 // Returns the populated http request with lambda context, stage variables and APIGatewayProxyRequestContext as part of its context.
 // Access those using GetAPIGatewayContextFromContext, GetStageVarsFromContext and GetRuntimeContextFromContext functions in this package.
+// The context of a Lambda authorizer is available through GetAuthorizerFromContext.
 func (r *RequestAccessor) EventToRequestWithContext(ctx context.Context, req events.APIGatewayProxyRequest) (*http.Request, error) {
-	httpRequest, err := r.EventToRequest(req)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-	return addToContext(ctx, httpRequest, req), nil
+	return eventToRequestWithContext(ctx, &r.basePath, MapperAPIGatewayV1{}, req)
 }
 
 // EventToRequest converts an API Gateway proxy event into an http.Request object.
 // Returns the populated request maintaining headers
 func (r *RequestAccessor) EventToRequest(req events.APIGatewayProxyRequest) (*http.Request, error) {
-	decodedBody := []byte(req.Body)
-	if req.IsBase64Encoded {
-		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
-		if err != nil {
-			return nil, err
-		}
-		decodedBody = base64Body
-	}
+	return eventToRequest(&r.basePath, MapperAPIGatewayV1{}, req)
+}
 
-	path := req.Path
-	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
-		if strings.HasPrefix(path, r.stripBasePath) {
-			path = strings.Replace(path, r.stripBasePath, "", 1)
-		}
-	}
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-	serverAddress := "https://" + req.RequestContext.DomainName
-	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
-		serverAddress = customAddress
-	}
-	path = serverAddress + path
+// MapperAPIGatewayV1 is the EventMapper of API Gateway REST API (v1) proxy events.
+type MapperAPIGatewayV1 struct{}
 
-	if len(req.MultiValueQueryStringParameters) > 0 {
-		queryString := ""
-		for q, l := range req.MultiValueQueryStringParameters {
-			for _, v := range l {
-				if queryString != "" {
-					queryString += "&"
-				}
-				queryString += url.QueryEscape(q) + "=" + url.QueryEscape(v)
-			}
-		}
-		path += "?" + queryString
-	} else if len(req.QueryStringParameters) > 0 {
+func (MapperAPIGatewayV1) MapEvent(req events.APIGatewayProxyRequest) (HTTPEvent, error) {
+	return HTTPEvent{
+		Method: req.HTTPMethod,
+		Path:   req.Path,
 		// Support `QueryStringParameters` for backward compatibility.
 		// https://github.com/awslabs/aws-lambda-go-api-proxy/issues/37
-		queryString := ""
-		for q := range req.QueryStringParameters {
-			if queryString != "" {
-				queryString += "&"
-			}
-			queryString += url.QueryEscape(q) + "=" + url.QueryEscape(req.QueryStringParameters[q])
-		}
-		path += "?" + queryString
-	}
-
-	httpRequest, err := http.NewRequest(
-		strings.ToUpper(req.HTTPMethod),
-		path,
-		bytes.NewReader(decodedBody),
-	)
+		// API Gateway delivers decoded parameters, so they are escaped again.
+		RawQuery:        buildQuery(req.MultiValueQueryStringParameters, req.QueryStringParameters, true),
+		Header:          eventHeader(req.MultiValueHeaders, req.Headers),
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Host:            req.RequestContext.DomainName,
+		RemoteAddr:      req.RequestContext.Identity.SourceIP,
+		BasePath:        basePathV1(req),
+	}, nil
+}
 
-	if err != nil {
-		fmt.Printf("Could not convert request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
-		log.Println(err)
-		return nil, err
-	}
+func (MapperAPIGatewayV1) AddToContext(ctx context.Context, req *http.Request, event events.APIGatewayProxyRequest) *http.Request {
+	return addToContext(ctx, req, event)
+}
 
-	httpRequest.RemoteAddr = req.RequestContext.Identity.SourceIP
+// basePathV1 returns the base path mapping of a REST API event, which is in front of
+// the resource that matched. The path of REST API events never holds the stage.
+func basePathV1(req events.APIGatewayProxyRequest) string {
+	basePath, _ := routeBasePath(req.Path, req.Resource, req.PathParameters)
+	return basePath
+}
 
-	if req.MultiValueHeaders != nil {
-		for k, values := range req.MultiValueHeaders {
-			for _, value := range values {
-				httpRequest.Header.Add(k, value)
-			}
+// eventHeader builds the header of events with multi value and single value
+// headers. Multi value headers take precedence, as both are present in events of
+// front ends with multi value support enabled.
+func eventHeader(multi map[string][]string, single map[string]string) http.Header {
+	if multi != nil {
+		n := 0
+		for _, values := range multi {
+			n += len(values)
 		}
-	} else {
-		for h := range req.Headers {
-			httpRequest.Header.Add(h, req.Headers[h])
+		h := newHeaderValues(len(multi), n)
+		for k, values := range multi {
+			h.add(k, values...)
 		}
+		return h.header
 	}
 
-	httpRequest.RequestURI = httpRequest.URL.RequestURI()
+	h := newHeaderValues(len(single), len(single))
+	for k, value := range single {
+		h.add(k, value)
+	}
+	return h.header
+}
 
-	return httpRequest, nil
+// headerValues builds an http.Header whose values share one backing array, where
+// http.Header.Add allocates a slice for every key.
+type headerValues struct {
+	header http.Header
+	values []string
 }
 
-func addToHeader(req *http.Request, apiGwRequest events.APIGatewayProxyRequest) (*http.Request, error) {
-	stageVars, err := json.Marshal(apiGwRequest.StageVariables)
+func newHeaderValues(keys, values int) headerValues {
+	return headerValues{header: make(http.Header, keys), values: make([]string, 0, values)}
+}
+
+// add appends values to the canonical key, like http.Header.Add.
+func (h *headerValues) add(key string, values ...string) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	if existing, ok := h.header[key]; ok {
+		// the capacity of existing is its length, so this copies
+		h.header[key] = append(existing, values...)
+		return
+	}
+	start := len(h.values)
+	h.values = append(h.values, values...)
+	h.header[key] = h.values[start:len(h.values):len(h.values)]
+}
+
+func addToHeader(req *http.Request, stageVars map[string]string, apiGwContext events.APIGatewayProxyRequestContext) error {
+	stageVarsJSON, err := json.Marshal(stageVars)
 	if err != nil {
-		log.Println("Could not marshal stage variables for custom header")
-		return nil, err
+		appLog.Error("Could not marshal stage variables for custom header", "err", err)
+		return err
 	}
-	req.Header.Set(APIGwStageVarsHeader, string(stageVars))
-	apiGwContext, err := json.Marshal(apiGwRequest.RequestContext)
+	req.Header.Set(APIGwStageVarsHeader, string(stageVarsJSON))
+	apiGwContextJSON, err := json.Marshal(apiGwContext)
 	if err != nil {
-		log.Println("Could not Marshal API GW context for custom header")
-		return req, err
+		appLog.Error("Could not Marshal API GW context for custom header", "err", err)
+		return err
 	}
-	req.Header.Set(APIGwContextHeader, string(apiGwContext))
-	return req, nil
+	req.Header.Set(APIGwContextHeader, string(apiGwContextJSON))
+	return nil
 }
 
 func addToContext(ctx context.Context, req *http.Request, apiGwRequest events.APIGatewayProxyRequest) *http.Request {
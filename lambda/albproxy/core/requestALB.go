@@ -3,14 +3,11 @@
 package core
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"log"
+	"net"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -19,174 +16,164 @@ import (
 
 const (
 	// ALBContextHeader is the custom header key used to store the
-	// ALB ELB context. To access the Context properties use the
-	// GetALBContext method of the RequestAccessorALB object.
+	// ALB ELB context when requested with AddContextHeaders. To access the
+	// Context properties use the GetContextALB method of the RequestAccessorALB object.
 	ALBContextHeader = "X-GoLambdaProxy-ALB-Context"
 )
 
 // RequestAccessorALB objects give access to custom ALB Target Group properties
 // in the request.
 type RequestAccessorALB struct {
-	stripBasePath string
+	basePath
 }
 
-// GetALBContext extracts the ALB context object from a request's custom header.
+// GetContextALB returns the ALB context of a request converted by ProxyEventToHTTPRequest
+// or EventToRequestWithContext, or else the context stored in the custom header by
+// AddContextHeaders.
 // Returns a populated events.ALBTargetGroupRequestContext object from the request.
 func (r *RequestAccessorALB) GetContextALB(req *http.Request) (events.ALBTargetGroupRequestContext, error) {
+	if albContext, ok := GetTargetGroupRequetFromContextALB(req.Context()); ok {
+		return albContext, nil
+	}
 	if req.Header.Get(ALBContextHeader) == "" {
 		return events.ALBTargetGroupRequestContext{}, errors.New("no context header in request")
 	}
 	context := events.ALBTargetGroupRequestContext{}
 	err := json.Unmarshal([]byte(req.Header.Get(ALBContextHeader)), &context)
 	if err != nil {
-		log.Println("Error while unmarshalling context")
-		log.Println(err)
+		appLog.Error("Error while unmarshalling context", "err", err)
 		return events.ALBTargetGroupRequestContext{}, err
 	}
 	return context, nil
 }
 
-// StripBasePath instructs the RequestAccessor object that the given base
-// path should be removed from the request path before sending it to the
-// framework for routing. This is used when API Gateway is configured with
-// base path mappings in custom domain names.
-func (r *RequestAccessorALB) StripBasePath(basePath string) string {
-	if strings.Trim(basePath, " ") == "" {
-		r.stripBasePath = ""
-		return ""
-	}
-
-	newBasePath := basePath
-	if !strings.HasPrefix(newBasePath, "/") {
-		newBasePath = "/" + newBasePath
-	}
-
-	if strings.HasSuffix(newBasePath, "/") {
-		newBasePath = newBasePath[:len(newBasePath)-1]
+// AddContextHeaders serializes the ALB context of req into the ALBContextHeader
+// header, for handlers that forward the request to services reading it.
+func (r *RequestAccessorALB) AddContextHeaders(req *http.Request) error {
+	v, ok := req.Context().Value(ctxKey{}).(requestContextALB)
+	if !ok {
+		return errors.New("no ALB context in request")
 	}
-
-	r.stripBasePath = newBasePath
-
-	return newBasePath
+	return addToHeaderALB(req, v.albContext)
 }
 
 // ProxyEventToHTTPRequest converts an ALB Target Group Request event into a http.Request object.
-// Returns the populated http request with additional custom header for the ALB context.
-// To access these properties use the GetALBContext method of the RequestAccessorALB object.
+// Returns the populated http request with the ALB context as part of its context.
+// To access these properties use the GetContextALB method of the RequestAccessorALB object.
 func (r *RequestAccessorALB) ProxyEventToHTTPRequest(req events.ALBTargetGroupRequest) (*http.Request, error) {
 	httpRequest, err := r.EventToRequest(req)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-	return addToHeaderALB(httpRequest, req)
+	return addToContextALB(httpRequest.Context(), httpRequest, req), nil
 }
 
 // EventToRequestWithContext converts an ALB Target Group Request event and context into an http.Request object.
 // Returns the populated http request with lambda context, ALB TargetGroup RequestContext as part of its context.
 func (r *RequestAccessorALB) EventToRequestWithContext(ctx context.Context, req events.ALBTargetGroupRequest) (*http.Request, error) {
-	httpRequest, err := r.EventToRequest(req)
-	if err != nil {
-		appLog.Debug("Failed to convert event to request", "err", err)
-		return nil, err
-	}
-	return addToContextALB(ctx, httpRequest, req), nil
+	return eventToRequestWithContext(ctx, &r.basePath, MapperALB{}, req)
 }
 
 // EventToRequest converts an ALB TargetGroup event into an http.Request object.
 // Returns the populated request maintaining headers
 func (r *RequestAccessorALB) EventToRequest(req events.ALBTargetGroupRequest) (*http.Request, error) {
-	decodedBody := []byte(req.Body)
-	if req.IsBase64Encoded {
-		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
-		if err != nil {
-			appLog.Info("Error in base64 decode", "err", err)
-			return nil, err
-		}
-		decodedBody = base64Body
-	}
+	return eventToRequest(&r.basePath, MapperALB{}, req)
+}
 
-	path := req.Path
-	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
-		if strings.HasPrefix(path, r.stripBasePath) {
-			path = strings.Replace(path, r.stripBasePath, "", 1)
-		}
-	}
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-	serverAddress := "https://" + req.Headers["host"]
-	//  if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
-	//  	serverAddress = customAddress
-	//  }
-	path = serverAddress + path
-
-	if len(req.MultiValueQueryStringParameters) > 0 {
-		queryString := ""
-		for q, l := range req.MultiValueQueryStringParameters {
-			for _, v := range l {
-				if queryString != "" {
-					queryString += "&"
-				}
-				queryString += url.QueryEscape(q) + "=" + url.QueryEscape(v)
-			}
-		}
-		path += "?" + queryString
-	} else if len(req.QueryStringParameters) > 0 {
+// MapperALB is the EventMapper of ALB Target Group events, in either header mode.
+type MapperALB struct{}
+
+func (MapperALB) MapEvent(req events.ALBTargetGroupRequest) (HTTPEvent, error) {
+	scheme, host := serverAddressALB(req)
+	return HTTPEvent{
+		Method: req.HTTPMethod,
+		Path:   req.Path,
 		// Support `QueryStringParameters` for backward compatibility.
 		// https://github.com/awslabs/aws-lambda-go-api-proxy/issues/37
-		queryString := ""
-		for q := range req.QueryStringParameters {
-			if queryString != "" {
-				queryString += "&"
-			}
-			queryString += url.QueryEscape(q) + "=" + url.QueryEscape(req.QueryStringParameters[q])
-		}
-		path += "?" + queryString
-	}
+		// ALB delivers parameters exactly as they appeared in the URL.
+		RawQuery:        buildQuery(req.MultiValueQueryStringParameters, req.QueryStringParameters, false),
+		Header:          eventHeader(req.MultiValueHeaders, req.Headers),
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Scheme:          scheme,
+		Host:            host,
+		RemoteAddr:      remoteAddrALB(headerALB(req, "x-forwarded-for")),
+	}, nil
+}
 
-	httpRequest, err := http.NewRequest(
-		strings.ToUpper(req.HTTPMethod),
-		path,
-		bytes.NewReader(decodedBody),
-	)
+func (MapperALB) AddToContext(ctx context.Context, req *http.Request, event events.ALBTargetGroupRequest) *http.Request {
+	return addToContextALB(ctx, req, event)
+}
 
-	if err != nil {
-		appLog.Info("Could not convert request to http.Request", "method", req.HTTPMethod, "path", req.Path)
-		return nil, err
-	}
+// IsMultiValueALB reports whether the event was sent by a target group with
+// lambda.multi_value_headers.enabled. The response must use the same mode.
+func IsMultiValueALB(req events.ALBTargetGroupRequest) bool {
+	return req.MultiValueHeaders != nil
+}
 
+// headerALB returns the last value of a lower case header in either header mode.
+func headerALB(req events.ALBTargetGroupRequest, key string) string {
 	if req.MultiValueHeaders != nil {
-		for k, values := range req.MultiValueHeaders {
-			for _, value := range values {
-				httpRequest.Header.Add(k, value)
-			}
-		}
-	} else {
-		for h := range req.Headers {
-			httpRequest.Header.Add(h, req.Headers[h])
+		if values := req.MultiValueHeaders[key]; len(values) > 0 {
+			return values[len(values)-1]
 		}
+		return ""
+	}
+	return req.Headers[key]
+}
+
+// serverAddressALB returns the scheme and host[:port] from the host and X-Forwarded
+// headers ALB adds to every request. The port is only kept when it is not the
+// default one for the scheme.
+func serverAddressALB(req events.ALBTargetGroupRequest) (string, string) {
+	scheme := strings.ToLower(headerALB(req, "x-forwarded-proto"))
+	if scheme != "http" {
+		scheme = "https"
 	}
 
-	httpRequest.RequestURI = httpRequest.URL.RequestURI()
+	host := headerALB(req, "host")
+	port := headerALB(req, "x-forwarded-port")
+	if _, _, err := net.SplitHostPort(host); err != nil && port != "" && host != "" {
+		if !(scheme == "https" && port == "443") && !(scheme == "http" && port == "80") {
+			host = net.JoinHostPort(host, port)
+		}
+	}
+	return scheme, host
+}
 
-	return httpRequest, nil
+// remoteAddrALB returns the client address from X-Forwarded-For. ALB appends the
+// address it received the connection from, so the last entry is the one that
+// cannot be spoofed by the client.
+func remoteAddrALB(forwardedFor string) string {
+	if forwardedFor == "" {
+		return ""
+	}
+	parts := strings.Split(forwardedFor, ",")
+	return strings.TrimSpace(parts[len(parts)-1])
 }
 
-func addToHeaderALB(req *http.Request, albRequest events.ALBTargetGroupRequest) (*http.Request, error) {
-	albContext, err := json.Marshal(albRequest.RequestContext)
+func addToHeaderALB(req *http.Request, albContext events.ALBTargetGroupRequestContext) error {
+	albContextJSON, err := json.Marshal(albContext)
 	if err != nil {
-		appLog.Info("Could not Marshal ALB context for custom header")
-		return req, err
+		appLog.Error("Could not Marshal ALB context for custom header", "err", err)
+		return err
 	}
-	req.Header.Set(ALBContextHeader, string(albContext))
-	return req, nil
+	req.Header.Set(ALBContextHeader, string(albContextJSON))
+	return nil
 }
 
 // adds context data to http request so we can pass
 func addToContextALB(ctx context.Context, req *http.Request, albRequest events.ALBTargetGroupRequest) *http.Request {
 	lc, _ := lambdacontext.FromContext(ctx)
-	rc := requestContextALB{lambdaContext: lc, albContext: albRequest.RequestContext}
+	rc := requestContextALB{
+		lambdaContext: lc,
+		albContext:    albRequest.RequestContext,
+		traceID:       req.Header.Get("X-Amzn-Trace-Id"),
+		sourceIP:      remoteIP(req.RemoteAddr),
+		userAgent:     req.UserAgent(),
+		host:          req.Host,
+		oidcData:      req.Header.Get("X-Amzn-Oidc-Data"),
+	}
 	ctx = context.WithValue(ctx, ctxKey{}, rc)
 	return req.WithContext(ctx)
 }
@@ -206,4 +193,11 @@ func GetRuntimeContextFromContextALB(ctx context.Context) (*lambdacontext.Lambda
 type requestContextALB struct {
 	lambdaContext *lambdacontext.LambdaContext
 	albContext    events.ALBTargetGroupRequestContext
+
+	// ALB has no request context of its own, RequestInfo reads the headers
+	traceID   string
+	sourceIP  string
+	userAgent string
+	host      string
+	oidcData  string
 }
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// RequestAccessorLattice converts the events VPC Lattice sends to Lambda targets into
+// http.Requests, for target groups with event structure version V1 and V2.
+// Only V2 events carry a request context with the caller identity.
+type RequestAccessorLattice struct {
+	basePath
+}
+
+// EventToRequestWithContext converts a V1 Lattice event and context into an http.Request object.
+// Returns the populated http request with lambda context as part of its context.
+func (r *RequestAccessorLattice) EventToRequestWithContext(ctx context.Context, req VPCLatticeRequest) (*http.Request, error) {
+	return eventToRequestWithContext(ctx, &r.basePath, MapperLattice{}, req)
+}
+
+// EventToRequest converts a V1 Lattice event into an http.Request object.
+// Returns the populated request maintaining headers
+func (r *RequestAccessorLattice) EventToRequest(req VPCLatticeRequest) (*http.Request, error) {
+	return eventToRequest(&r.basePath, MapperLattice{}, req)
+}
+
+// EventToRequestV2WithContext converts a V2 Lattice event and context into an http.Request object.
+// Returns the populated http request with lambda context and the Lattice request context as part of its context.
+// Access those using GetLatticeContextFromContext, GetLatticeIdentityFromContext and
+// GetRuntimeContextFromContextLattice functions in this package.
+func (r *RequestAccessorLattice) EventToRequestV2WithContext(ctx context.Context, req VPCLatticeRequestV2) (*http.Request, error) {
+	return eventToRequestWithContext(ctx, &r.basePath, MapperLatticeV2{}, req)
+}
+
+// EventToRequestV2 converts a V2 Lattice event into an http.Request object.
+// Returns the populated request maintaining headers
+func (r *RequestAccessorLattice) EventToRequestV2(req VPCLatticeRequestV2) (*http.Request, error) {
+	return eventToRequest(&r.basePath, MapperLatticeV2{}, req)
+}
+
+// MapperLattice is the EventMapper of V1 VPC Lattice events.
+type MapperLattice struct{}
+
+func (MapperLattice) MapEvent(req VPCLatticeRequest) (HTTPEvent, error) {
+	header := eventHeader(nil, req.Headers)
+	return HTTPEvent{
+		Method:          req.Method,
+		Path:            req.RawPath,
+		RawQuery:        buildQuery(nil, req.QueryStringParameters, true),
+		Header:          header,
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Host:            header.Get("Host"),
+		RemoteAddr:      remoteAddrALB(header.Get("X-Forwarded-For")),
+	}, nil
+}
+
+func (MapperLattice) AddToContext(ctx context.Context, req *http.Request, event VPCLatticeRequest) *http.Request {
+	return addToContextLattice(ctx, req, EventSourceVPCLattice, VPCLatticeRequestContext{})
+}
+
+// MapperLatticeV2 is the EventMapper of V2 VPC Lattice events.
+type MapperLatticeV2 struct{}
+
+func (MapperLatticeV2) MapEvent(req VPCLatticeRequestV2) (HTTPEvent, error) {
+	header := eventHeader(req.Headers, nil)
+	return HTTPEvent{
+		Method:          req.Method,
+		Path:            req.Path,
+		RawQuery:        buildQuery(req.QueryStringParameters, nil, true),
+		Header:          header,
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Host:            header.Get("Host"),
+		RemoteAddr:      remoteAddrALB(header.Get("X-Forwarded-For")),
+	}, nil
+}
+
+func (MapperLatticeV2) AddToContext(ctx context.Context, req *http.Request, event VPCLatticeRequestV2) *http.Request {
+	return addToContextLattice(ctx, req, EventSourceVPCLatticeV2, event.RequestContext)
+}
+
+func addToContextLattice(ctx context.Context, req *http.Request, source EventSource, latticeContext VPCLatticeRequestContext) *http.Request {
+	lc, _ := lambdacontext.FromContext(ctx)
+	rc := requestContextLattice{
+		lambdaContext:  lc,
+		source:         source,
+		latticeContext: latticeContext,
+		traceID:        req.Header.Get("X-Amzn-Trace-Id"),
+		sourceIP:       remoteIP(req.RemoteAddr),
+		userAgent:      req.UserAgent(),
+		host:           req.Host,
+	}
+	ctx = context.WithValue(ctx, ctxKey{}, rc)
+	return req.WithContext(ctx)
+}
+
+// GetLatticeContextFromContext retrieve the request context of a V2 Lattice event from context.Context.
+// It is empty for V1 events.
+func GetLatticeContextFromContext(ctx context.Context) (VPCLatticeRequestContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextLattice)
+	return v.latticeContext, ok
+}
+
+// GetLatticeIdentityFromContext retrieve the caller identity of the Lattice auth context
+// from context.Context. It returns false outside Lattice requests and for V1 events, which
+// carry no identity. Principal is only set when Identity.Type is AWS_IAM.
+func GetLatticeIdentityFromContext(ctx context.Context) (VPCLatticeIdentity, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextLattice)
+	return v.latticeContext.Identity, ok && v.source == EventSourceVPCLatticeV2
+}
+
+// GetRuntimeContextFromContextLattice retrieve Lambda Runtime Context from context.Context
+func GetRuntimeContextFromContextLattice(ctx context.Context) (*lambdacontext.LambdaContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextLattice)
+	return v.lambdaContext, ok
+}
+
+type requestContextLattice struct {
+	lambdaContext  *lambdacontext.LambdaContext
+	source         EventSource
+	latticeContext VPCLatticeRequestContext
+
+	// Lattice has no request ID or client details in its context, RequestInfo reads the headers
+	traceID   string
+	sourceIP  string
+	userAgent string
+	host      string
+}
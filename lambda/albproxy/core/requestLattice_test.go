@@ -0,0 +1,170 @@
+package core_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const latticeEventV2 = `{
+	"version": "2.0",
+	"path": "/api/orders",
+	"method": "POST",
+	"headers": {
+		"host": ["orders-0abc.7d67968.vpc-lattice-svcs.ap-south-1.on.aws"],
+		"user-agent": ["curl/8"],
+		"x-forwarded-for": ["10.0.1.5"],
+		"accept": ["text/html", "application/json"]
+	},
+	"queryStringParameters": {"status": ["open", "paid"], "q": "a b"},
+	"body": "eyJpZCI6MX0=",
+	"isBase64Encoded": true,
+	"requestContext": {
+		"serviceNetworkArn": "arn:aws:vpc-lattice:ap-south-1:000000000000:servicenetwork/sn-1",
+		"serviceArn": "arn:aws:vpc-lattice:ap-south-1:000000000000:service/svc-1",
+		"targetGroupArn": "arn:aws:vpc-lattice:ap-south-1:000000000000:targetgroup/tg-1",
+		"identity": {
+			"sourceVpcArn": "arn:aws:ec2:ap-south-1:000000000000:vpc/vpc-1",
+			"type": "AWS_IAM",
+			"principal": "arn:aws:sts::000000000000:assumed-role/billing/i-1",
+			"principalOrgID": "o-1",
+			"sessionName": "i-1"
+		},
+		"region": "ap-south-1",
+		"timeEpoch": "1700000000000000"
+	}
+}`
+
+var _ = Describe("RequestAccessorLattice", func() {
+	It("converts V1 events", func() {
+		accessor := core.RequestAccessorLattice{}
+		req, err := accessor.EventToRequestWithContext(context.Background(), core.VPCLatticeRequest{
+			RawPath: "/orders/1",
+			Method:  "get",
+			Headers: map[string]string{
+				"host":            "orders.example.com",
+				"x-forwarded-for": "10.0.1.5",
+			},
+			QueryStringParameters: map[string]string{"q": "a b"},
+		})
+		Expect(err).To(BeNil())
+		Expect(req.Method).To(Equal(http.MethodGet))
+		Expect(req.URL.String()).To(Equal("https://orders.example.com/orders/1?q=a+b"))
+		Expect(req.RemoteAddr).To(Equal("10.0.1.5:0"))
+
+		_, ok := core.GetLatticeIdentityFromContext(req.Context())
+		Expect(ok).To(BeFalse())
+		info, ok := core.RequestInfo(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(info.Source).To(Equal(core.EventSourceVPCLattice))
+		Expect(info.Claims).To(BeNil())
+	})
+
+	It("keeps a query string left in the V1 raw path", func() {
+		accessor := core.RequestAccessorLattice{}
+		req, err := accessor.EventToRequest(core.VPCLatticeRequest{RawPath: "/orders?status=open", Method: "GET"})
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders"))
+		Expect(req.URL.RawQuery).To(Equal("status=open"))
+	})
+
+	It("converts V2 events with the caller identity", func() {
+		var event core.VPCLatticeRequestV2
+		Expect(json.Unmarshal([]byte(latticeEventV2), &event)).To(Succeed())
+
+		accessor := core.RequestAccessorLattice{}
+		accessor.StripBasePath("/api/")
+		req, err := accessor.EventToRequestV2WithContext(context.Background(), event)
+		Expect(err).To(BeNil())
+		Expect(req.Method).To(Equal(http.MethodPost))
+		Expect(req.Host).To(Equal("orders-0abc.7d67968.vpc-lattice-svcs.ap-south-1.on.aws"))
+		Expect(req.URL.Path).To(Equal("/orders"))
+		Expect(req.URL.Query()["status"]).To(Equal([]string{"open", "paid"}))
+		Expect(req.URL.Query().Get("q")).To(Equal("a b"))
+		Expect(req.Header.Values("Accept")).To(Equal([]string{"text/html", "application/json"}))
+		body, _ := io.ReadAll(req.Body)
+		Expect(string(body)).To(Equal(`{"id":1}`))
+
+		id, ok := core.GetLatticeIdentityFromContext(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(id.Type).To(Equal("AWS_IAM"))
+		Expect(id.Principal).To(Equal("arn:aws:sts::000000000000:assumed-role/billing/i-1"))
+
+		rc, ok := core.GetLatticeContextFromContext(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(rc.TargetGroupARN).To(Equal("arn:aws:vpc-lattice:ap-south-1:000000000000:targetgroup/tg-1"))
+
+		info, ok := core.RequestInfo(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(info.Source).To(Equal(core.EventSourceVPCLatticeV2))
+		Expect(info.SourceIP).To(Equal("10.0.1.5"))
+		Expect(info.UserAgent).To(Equal("curl/8"))
+		Expect(info.Claims).To(Equal(map[string]string{
+			"sourceVpcArn":   "arn:aws:ec2:ap-south-1:000000000000:vpc/vpc-1",
+			"type":           "AWS_IAM",
+			"principal":      "arn:aws:sts::000000000000:assumed-role/billing/i-1",
+			"principalOrgID": "o-1",
+			"sessionName":    "i-1",
+		}))
+	})
+
+	It("rejects invalid base64 bodies", func() {
+		accessor := core.RequestAccessorLattice{}
+		_, err := accessor.EventToRequestV2(core.VPCLatticeRequestV2{Path: "/", Method: "POST", Body: "%%%", IsBase64Encoded: true})
+		Expect(err).ToNot(BeNil())
+	})
+})
+
+var _ = Describe("ProxyResponseWriterLattice", func() {
+	It("returns single value headers with a status description", func() {
+		w := core.NewProxyResponseWriterLattice()
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(resp.StatusDescription).To(Equal("201 Created"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Vary", "Accept,Origin"))
+		Expect(resp.Body).To(Equal("created"))
+		Expect(resp.IsBase64Encoded).To(BeFalse())
+	})
+
+	It("base64 encodes binary bodies", func() {
+		w := core.NewProxyResponseWriterLattice()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0xff, 0xfe})
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		Expect(resp.Body).To(Equal(base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe})))
+	})
+
+	It("fails without status", func() {
+		_, err := core.NewProxyResponseWriterLattice().GetProxyResponse()
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("replaces responses over the payload limit", func() {
+		w := core.NewProxyResponseWriterLattice()
+		w.SetPayloadOptions(core.PayloadOptions{MaxSize: 2048, DisableCompression: true})
+		w.Write(make([]byte, 4096))
+
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(resp.StatusDescription).To(Equal("413 Request Entity Too Large"))
+		Expect(resp.Body).To(ContainSubstring("exceeds the payload limit of 2048 bytes"))
+	})
+})
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+const (
+	// SQSMessageIDHeader carries the SQS message ID on synthetic requests.
+	SQSMessageIDHeader = "X-Amz-Sqs-Message-Id"
+
+	// SQSQueueHeader carries the name of the source queue on synthetic requests.
+	SQSQueueHeader = "X-Amz-Sqs-Queue"
+
+	// SQSAttributeHeaderPrefix prefixes the string message attributes copied to
+	// synthetic request headers.
+	SQSAttributeHeaderPrefix = "X-Amz-Sqs-Attr-"
+)
+
+// RequestAccessorSQS converts SQS messages into synthetic POST requests so they
+// can be served by the same http.Handlers as web traffic.
+type RequestAccessorSQS struct {
+	routeAttribute string
+}
+
+// RouteByAttribute instructs the RequestAccessorSQS object to take the request
+// path from the given string message attribute. Messages without the attribute,
+// or when no attribute is set, are routed to /<queue name>.
+func (r *RequestAccessorSQS) RouteByAttribute(name string) string {
+	r.routeAttribute = strings.TrimSpace(name)
+	return r.routeAttribute
+}
+
+// EventToRequestWithContext converts an SQS message and context into an http.Request object.
+// Returns the populated http request with lambda context and the SQS message as part of its context.
+// Access those using GetSQSMessageFromContext and GetRuntimeContextFromContextSQS.
+func (r *RequestAccessorSQS) EventToRequestWithContext(ctx context.Context, msg events.SQSMessage) (*http.Request, error) {
+	return eventToRequestWithContext(ctx, &basePath{}, MapperSQS{RouteAttribute: r.routeAttribute}, msg)
+}
+
+// EventToRequest converts an SQS message into a POST http.Request object.
+// The message body becomes the request body and string message attributes
+// become X-Amz-Sqs-Attr-* headers.
+func (r *RequestAccessorSQS) EventToRequest(msg events.SQSMessage) (*http.Request, error) {
+	return eventToRequest(&basePath{}, MapperSQS{RouteAttribute: r.routeAttribute}, msg)
+}
+
+// MapperSQS is the EventMapper of SQS messages, see RequestAccessorSQS.
+type MapperSQS struct {
+	// RouteAttribute names the string message attribute holding the request path,
+	// see RequestAccessorSQS.RouteByAttribute.
+	RouteAttribute string
+}
+
+func (m MapperSQS) MapEvent(msg events.SQSMessage) (HTTPEvent, error) {
+	queue := QueueNameSQS(msg.EventSourceARN)
+
+	path := "/" + queue
+	if attr, ok := msg.MessageAttributes[m.RouteAttribute]; m.RouteAttribute != "" && ok && attr.StringValue != nil {
+		path = *attr.StringValue
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid([]byte(msg.Body)) {
+		contentType = "application/json"
+	}
+	header := make(http.Header, len(msg.MessageAttributes)+3)
+	header.Set(contentTypeHeaderKey, contentType)
+	header.Set(SQSMessageIDHeader, msg.MessageId)
+	header.Set(SQSQueueHeader, queue)
+
+	for name, attr := range msg.MessageAttributes {
+		if attr.StringValue != nil {
+			header.Set(SQSAttributeHeaderPrefix+name, *attr.StringValue)
+		}
+	}
+
+	return HTTPEvent{
+		Method: http.MethodPost,
+		Path:   path,
+		Header: header,
+		Body:   msg.Body,
+		Host:   "sqs." + msg.AWSRegion + ".amazonaws.com",
+	}, nil
+}
+
+func (MapperSQS) AddToContext(ctx context.Context, req *http.Request, msg events.SQSMessage) *http.Request {
+	return addToContextSQS(ctx, req, msg)
+}
+
+// QueueNameSQS returns the queue name from a queue ARN.
+func QueueNameSQS(arn string) string {
+	return arn[strings.LastIndex(arn, ":")+1:]
+}
+
+// IsFifoSQS reports whether the queue ARN belongs to a FIFO queue.
+func IsFifoSQS(arn string) bool {
+	return strings.HasSuffix(arn, ".fifo")
+}
+
+func addToContextSQS(ctx context.Context, req *http.Request, msg events.SQSMessage) *http.Request {
+	lc, _ := lambdacontext.FromContext(ctx)
+	rc := requestContextSQS{lambdaContext: lc, message: msg}
+	ctx = context.WithValue(ctx, ctxKey{}, rc)
+	return req.WithContext(ctx)
+}
+
+// GetSQSMessageFromContext retrieve the SQS message from context.Context
+func GetSQSMessageFromContext(ctx context.Context) (events.SQSMessage, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextSQS)
+	return v.message, ok
+}
+
+// GetRuntimeContextFromContextSQS retrieve Lambda Runtime Context from context.Context
+func GetRuntimeContextFromContextSQS(ctx context.Context) (*lambdacontext.LambdaContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextSQS)
+	return v.lambdaContext, ok
+}
+
+type requestContextSQS struct {
+	lambdaContext *lambdacontext.LambdaContext
+	message       events.SQSMessage
+}
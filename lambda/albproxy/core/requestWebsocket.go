@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+const (
+	// WebsocketConnectRoute is the route key of the event sent when a client connects.
+	WebsocketConnectRoute = "$connect"
+
+	// WebsocketDisconnectRoute is the route key of the event sent when a client disconnects.
+	WebsocketDisconnectRoute = "$disconnect"
+
+	// WebsocketDefaultRoute is the route key of messages that match no other route.
+	WebsocketDefaultRoute = "$default"
+
+	// WebsocketConnectionIDHeader carries the connection ID on synthetic requests.
+	WebsocketConnectionIDHeader = "X-Amz-Websocket-Connection-Id"
+
+	// WebsocketRouteKeyHeader carries the route key on synthetic requests.
+	WebsocketRouteKeyHeader = "X-Amz-Websocket-Route-Key"
+)
+
+// RequestAccessorWebsocket converts API Gateway WebSocket events into http.Requests.
+// $connect becomes a GET request carrying the handshake headers and query, every other
+// route a POST request carrying the message body. The request path is /<route key>,
+// so $connect is served at /$connect and a route key such as orders/update at
+// /orders/update.
+type RequestAccessorWebsocket struct{}
+
+// EventToRequestWithContext converts a WebSocket event and context into an http.Request object.
+// Returns the populated http request with lambda context and the WebSocket request context as part of its context.
+// Access those using GetWebsocketContextFromContext and GetRuntimeContextFromContextWebsocket.
+func (r *RequestAccessorWebsocket) EventToRequestWithContext(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (*http.Request, error) {
+	return eventToRequestWithContext(ctx, &basePath{}, MapperWebsocket{}, req)
+}
+
+// EventToRequest converts a WebSocket event into an http.Request object.
+func (r *RequestAccessorWebsocket) EventToRequest(req events.APIGatewayWebsocketProxyRequest) (*http.Request, error) {
+	return eventToRequest(&basePath{}, MapperWebsocket{}, req)
+}
+
+// MapperWebsocket is the EventMapper of API Gateway WebSocket events.
+type MapperWebsocket struct{}
+
+func (MapperWebsocket) MapEvent(req events.APIGatewayWebsocketProxyRequest) (HTTPEvent, error) {
+	routeKey := req.RequestContext.RouteKey
+	method := http.MethodPost
+	if routeKey == WebsocketConnectRoute {
+		method = http.MethodGet
+	}
+
+	header := eventHeader(req.MultiValueHeaders, req.Headers)
+	header.Set(WebsocketConnectionIDHeader, req.RequestContext.ConnectionID)
+	header.Set(WebsocketRouteKeyHeader, routeKey)
+
+	return HTTPEvent{
+		Method: method,
+		Path:   (&url.URL{Path: "/" + routeKey}).EscapedPath(),
+		// $connect is the only event with a query string, delivered decoded like REST APIs.
+		RawQuery:        buildQuery(req.MultiValueQueryStringParameters, req.QueryStringParameters, true),
+		Header:          header,
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Host:            req.RequestContext.DomainName,
+		RemoteAddr:      req.RequestContext.Identity.SourceIP,
+	}, nil
+}
+
+func (MapperWebsocket) AddToContext(ctx context.Context, req *http.Request, event events.APIGatewayWebsocketProxyRequest) *http.Request {
+	return addToContextWebsocket(ctx, req, event)
+}
+
+// WebsocketAuthorizerValue returns a string value set in the authorizer context of a
+// WebSocket request, or "" if it is missing.
+func WebsocketAuthorizerValue(rc events.APIGatewayWebsocketProxyRequestContext, key string) string {
+	authorizer, _ := rc.Authorizer.(map[string]interface{})
+	return AuthorizerValue(authorizer, key)
+}
+
+// WebsocketCallbackURL returns the URL of the API Gateway management API used to send
+// messages to the connections of the API that sent the request. It is built from the
+// API ID and the AWS_REGION of the function, as the base path mapping of a custom
+// domain usually differs from the stage. Without them, as in local runs, the domain
+// name of the request is used.
+func WebsocketCallbackURL(rc events.APIGatewayWebsocketProxyRequestContext) string {
+	host := rc.DomainName
+	if region := os.Getenv("AWS_REGION"); rc.APIID != "" && region != "" {
+		host = rc.APIID + ".execute-api." + region + ".amazonaws.com"
+	}
+	return "https://" + host + "/" + strings.Trim(rc.Stage, "/")
+}
+
+func addToContextWebsocket(ctx context.Context, req *http.Request, wsRequest events.APIGatewayWebsocketProxyRequest) *http.Request {
+	lc, _ := lambdacontext.FromContext(ctx)
+	rc := requestContextWebsocket{lambdaContext: lc, websocketContext: wsRequest.RequestContext, stageVars: wsRequest.StageVariables}
+	ctx = context.WithValue(ctx, ctxKey{}, rc)
+	return req.WithContext(ctx)
+}
+
+// GetWebsocketContextFromContext retrieve APIGatewayWebsocketProxyRequestContext from context.Context
+func GetWebsocketContextFromContext(ctx context.Context) (events.APIGatewayWebsocketProxyRequestContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextWebsocket)
+	return v.websocketContext, ok
+}
+
+// GetRuntimeContextFromContextWebsocket retrieve Lambda Runtime Context from context.Context
+func GetRuntimeContextFromContextWebsocket(ctx context.Context) (*lambdacontext.LambdaContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextWebsocket)
+	return v.lambdaContext, ok
+}
+
+// GetStageVarsFromContextWebsocket retrieve stage variables from context
+func GetStageVarsFromContextWebsocket(ctx context.Context) (map[string]string, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContextWebsocket)
+	return v.stageVars, ok
+}
+
+type requestContextWebsocket struct {
+	lambdaContext    *lambdacontext.LambdaContext
+	websocketContext events.APIGatewayWebsocketProxyRequestContext
+	stageVars        map[string]string
+}
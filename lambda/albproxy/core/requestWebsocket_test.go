@@ -0,0 +1,38 @@
+package core_test
+
+import (
+	"os"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebsocketCallbackURL", func() {
+	rc := events.APIGatewayWebsocketProxyRequestContext{
+		APIID:      "abc123",
+		DomainName: "ws.example.com",
+		Stage:      "prod",
+	}
+
+	It("uses the execute-api endpoint of the API for custom domains", func() {
+		if old, ok := os.LookupEnv("AWS_REGION"); ok {
+			defer os.Setenv("AWS_REGION", old)
+		} else {
+			defer os.Unsetenv("AWS_REGION")
+		}
+		os.Setenv("AWS_REGION", "ap-south-1")
+		Expect(core.WebsocketCallbackURL(rc)).To(Equal("https://abc123.execute-api.ap-south-1.amazonaws.com/prod"))
+	})
+
+	It("falls back to the domain name without a region", func() {
+		if old, ok := os.LookupEnv("AWS_REGION"); ok {
+			defer os.Setenv("AWS_REGION", old)
+		}
+		os.Unsetenv("AWS_REGION")
+		Expect(core.WebsocketCallbackURL(rc)).To(Equal("https://ws.example.com/prod"))
+	})
+})
@@ -0,0 +1,301 @@
+package core_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProxyEventToHTTPRequest", func() {
+	It("keeps the API Gateway v1 context out of the headers until requested", func() {
+		accessor := core.RequestAccessor{}
+		req, err := accessor.ProxyEventToHTTPRequest(benchEventV1())
+		Expect(err).To(BeNil())
+		Expect(req.Header).ToNot(HaveKey(core.APIGwContextHeader))
+		Expect(req.Header).ToNot(HaveKey(core.APIGwStageVarsHeader))
+
+		apiGwContext, err := accessor.GetAPIGatewayContext(req)
+		Expect(err).To(BeNil())
+		Expect(apiGwContext.RequestID).To(Equal("c6af9ac6-7b61-11e6-9a41-93e8deadbeef"))
+		stageVars, err := accessor.GetAPIGatewayStageVars(req)
+		Expect(err).To(BeNil())
+		Expect(stageVars).To(HaveKeyWithValue("env", "prod"))
+
+		Expect(accessor.AddContextHeaders(req)).To(Succeed())
+		forwarded := httptest.NewRequest(http.MethodGet, "/", nil)
+		forwarded.Header = req.Header
+		apiGwContext, err = accessor.GetAPIGatewayContext(forwarded)
+		Expect(err).To(BeNil())
+		Expect(apiGwContext.Stage).To(Equal("prod"))
+		stageVars, err = accessor.GetAPIGatewayStageVars(forwarded)
+		Expect(err).To(BeNil())
+		Expect(stageVars).To(HaveKeyWithValue("tableName", "orders"))
+	})
+
+	It("keeps the API Gateway v2 context out of the headers until requested", func() {
+		accessor := core.RequestAccessorV2{}
+		req, err := accessor.ProxyEventToHTTPRequest(benchEventV2())
+		Expect(err).To(BeNil())
+		Expect(req.Header).ToNot(HaveKey(core.APIGwContextHeader))
+
+		apiGwContext, err := accessor.GetAPIGatewayContextV2(req)
+		Expect(err).To(BeNil())
+		Expect(apiGwContext.RequestID).To(Equal("JKJaXmPLvHcESHA="))
+
+		Expect(accessor.AddContextHeaders(req)).To(Succeed())
+		forwarded := httptest.NewRequest(http.MethodGet, "/", nil)
+		forwarded.Header = req.Header
+		apiGwContext, err = accessor.GetAPIGatewayContextV2(forwarded)
+		Expect(err).To(BeNil())
+		Expect(apiGwContext.HTTP.SourceIP).To(Equal("203.0.113.10"))
+		stageVars, err := accessor.GetAPIGatewayStageVars(forwarded)
+		Expect(err).To(BeNil())
+		Expect(stageVars).To(HaveKeyWithValue("env", "prod"))
+	})
+
+	It("keeps the ALB context out of the headers until requested", func() {
+		accessor := core.RequestAccessorALB{}
+		req, err := accessor.ProxyEventToHTTPRequest(benchEventALB())
+		Expect(err).To(BeNil())
+		Expect(req.Header).ToNot(HaveKey(core.ALBContextHeader))
+
+		albContext, err := accessor.GetContextALB(req)
+		Expect(err).To(BeNil())
+		Expect(albContext.ELB.TargetGroupArn).To(HaveSuffix("targetgroup/orders/6d0ecf831eec9f09"))
+
+		Expect(accessor.AddContextHeaders(req)).To(Succeed())
+		forwarded := httptest.NewRequest(http.MethodGet, "/", nil)
+		forwarded.Header = req.Header
+		albContext, err = accessor.GetContextALB(forwarded)
+		Expect(err).To(BeNil())
+		Expect(albContext.ELB.TargetGroupArn).To(HaveSuffix("targetgroup/orders/6d0ecf831eec9f09"))
+	})
+
+	It("fails to add context headers to requests without an event context", func() {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		Expect((&core.RequestAccessor{}).AddContextHeaders(req)).ToNot(Succeed())
+		Expect((&core.RequestAccessorALB{}).AddContextHeaders(req)).ToNot(Succeed())
+		_, err := (&core.RequestAccessorV2{}).GetAPIGatewayContextV2(req)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("merges header keys that differ in case", func() {
+		event := events.APIGatewayProxyRequest{
+			HTTPMethod:        http.MethodGet,
+			Path:              "/",
+			MultiValueHeaders: map[string][]string{"x-tag": {"a"}, "X-Tag": {"b"}, "accept": {"text/html", "*/*"}},
+		}
+		req, err := (&core.RequestAccessor{}).EventToRequest(event)
+		Expect(err).To(BeNil())
+		Expect(req.Header.Values("X-Tag")).To(ConsistOf("a", "b"))
+		Expect(req.Header.Values("Accept")).To(Equal([]string{"text/html", "*/*"}))
+		req.Header.Add("Accept", "application/json")
+		Expect(req.Header.Values("X-Tag")).To(ConsistOf("a", "b"))
+	})
+})
+
+var _ = Describe("DeriveBasePath", func() {
+	v1Event := func(path, resource string, params map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: path, Resource: resource, PathParameters: params}
+	}
+	v2Event := func(rawPath, routeKey, stage string, params map[string]string) events.APIGatewayV2HTTPRequest {
+		return events.APIGatewayV2HTTPRequest{
+			RawPath:        rawPath,
+			RouteKey:       routeKey,
+			PathParameters: params,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				Stage: stage,
+				HTTP:  events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+			},
+		}
+	}
+
+	It("strips the base path mapping in front of the resource of REST API events", func() {
+		accessor := core.RequestAccessor{}
+		accessor.DeriveBasePath(true)
+
+		for path, expected := range map[string][]string{
+			"/attendance/orders/42": {"/orders/{id}", "/orders/42", "/attendance"},
+			"/payroll/slips/3/pdf":  {"/{proxy+}", "/slips/3/pdf", "/payroll"},
+			"/payroll":              {"/", "/", "/payroll"},
+		} {
+			params := map[string]string{"id": "42", "proxy": "slips/3/pdf"}
+			req, err := accessor.ProxyEventToHTTPRequest(v1Event(path, expected[0], params))
+			Expect(err).To(BeNil())
+			Expect(req.URL.Path).To(Equal(expected[1]))
+			basePath, ok := core.GetBasePathFromContext(req.Context())
+			Expect(ok).To(BeTrue())
+			Expect(basePath).To(Equal(expected[2]))
+		}
+
+		req, err := accessor.EventToRequest(v1Event("/orders/42", "/orders/{id}", map[string]string{"id": "42"}))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders/42"))
+		_, ok := core.GetBasePathFromContext(req.Context())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("strips the mapping key or stage in front of the route of HTTP API events", func() {
+		accessor := core.RequestAccessorV2{}
+		accessor.DeriveBasePath(true)
+
+		req, err := accessor.EventToRequestWithContext(context.Background(), v2Event("/attendance/orders/42", "GET /orders/{id}", "$default", map[string]string{"id": "42"}))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders/42"))
+		basePath, _ := core.GetBasePathFromContext(req.Context())
+		Expect(basePath).To(Equal("/attendance"))
+
+		req, err = accessor.EventToRequestWithContext(context.Background(), v2Event("/prod/orders", "$default", "prod", nil))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders"))
+		basePath, _ = core.GetBasePathFromContext(req.Context())
+		Expect(basePath).To(Equal("/prod"))
+		_, ok := core.GetAPIGatewayV2ContextFromContext(req.Context())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("matches encoded paths against the decoded path parameters of HTTP API events", func() {
+		accessor := core.RequestAccessorV2{}
+		accessor.DeriveBasePath(true)
+
+		req, err := accessor.EventToRequest(v2Event("/attendance/orders/a%20b", "GET /orders/{id}", "$default", map[string]string{"id": "a b"}))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders/a b"))
+		Expect(req.URL.EscapedPath()).To(Equal("/orders/a%20b"))
+		basePath, _ := core.GetBasePathFromContext(req.Context())
+		Expect(basePath).To(Equal("/attendance"))
+
+		req, err = accessor.EventToRequest(v2Event("/attendance/files/q%231/r%C3%A9sum%C3%A9.pdf", "GET /files/{proxy+}", "$default", map[string]string{"proxy": "q#1/résumé.pdf"}))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/files/q#1/résumé.pdf"))
+		basePath, _ = core.GetBasePathFromContext(req.Context())
+		Expect(basePath).To(Equal("/attendance"))
+	})
+
+	It("does not find the mapping key of the $default route of HTTP API events", func() {
+		accessor := core.RequestAccessorV2{}
+		accessor.DeriveBasePath(true)
+
+		req, err := accessor.EventToRequest(v2Event("/attendance/orders/42", "$default", "$default", nil))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/attendance/orders/42"))
+		_, ok := core.GetBasePathFromContext(req.Context())
+		Expect(ok).To(BeFalse())
+
+		accessor.StripBasePaths("/attendance")
+		req, err = accessor.EventToRequest(v2Event("/attendance/orders/42", "$default", "$default", nil))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders/42"))
+	})
+
+	It("prefers configured base paths and is disabled by default", func() {
+		event := v1Event("/attendance/orders/42", "/orders/{id}", map[string]string{"id": "42"})
+
+		req, err := (&core.RequestAccessor{}).EventToRequest(event)
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/attendance/orders/42"))
+
+		accessor := core.RequestAccessor{}
+		accessor.DeriveBasePath(true)
+		accessor.StripBasePaths("/attendance/orders")
+		req, err = accessor.EventToRequest(event)
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/42"))
+	})
+
+	It("strips base paths at segment boundaries only", func() {
+		accessor := core.RequestAccessor{}
+		accessor.StripBasePath("/pay")
+
+		for path, expected := range map[string]string{"/pay/slips": "/slips", "/pay": "/", "/payroll": "/payroll"} {
+			req, err := accessor.EventToRequest(v1Event(path, "/{proxy+}", nil))
+			Expect(err).To(BeNil())
+			Expect(req.URL.Path).To(Equal(expected))
+		}
+	})
+
+	It("keeps the base paths of copied accessors when they are replaced", func() {
+		accessor := core.RequestAccessor{}
+		accessor.StripBasePaths("/attendance", "/payroll")
+		copied := accessor
+		accessor.StripBasePaths("/orders")
+
+		req, err := copied.EventToRequest(v1Event("/payroll/slips", "/{proxy+}", nil))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/slips"))
+		req, err = copied.EventToRequest(v1Event("/orders/42", "/{proxy+}", nil))
+		Expect(err).To(BeNil())
+		Expect(req.URL.Path).To(Equal("/orders/42"))
+	})
+})
+
+var _ = Describe("GetAuthorizerFromContext", func() {
+	It("returns the Lambda authorizer context of API Gateway requests", func() {
+		values := map[string]interface{}{core.AuthorizerEmailKey: "a@example.com", core.AuthorizerRoleKey: "admin"}
+
+		event := benchEventV1()
+		event.RequestContext.Authorizer = values
+		req, err := (&core.RequestAccessor{}).EventToRequestWithContext(context.Background(), event)
+		Expect(err).To(BeNil())
+		authorizer, ok := core.GetAuthorizerFromContext(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(authorizer).To(Equal(values))
+
+		eventV2 := benchEventV2()
+		eventV2.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{Lambda: values}
+		req, err = (&core.RequestAccessorV2{}).EventToRequestWithContext(context.Background(), eventV2)
+		Expect(err).To(BeNil())
+		authorizer, ok = core.GetAuthorizerFromContext(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(authorizer).To(Equal(values))
+	})
+
+	It("returns false without a Lambda authorizer", func() {
+		req, err := (&core.RequestAccessorV2{}).EventToRequestWithContext(context.Background(), benchEventV2())
+		Expect(err).To(BeNil())
+		_, ok := core.GetAuthorizerFromContext(req.Context())
+		Expect(ok).To(BeFalse())
+
+		req, err = (&core.RequestAccessorALB{}).EventToRequestWithContext(context.Background(), benchEventALB())
+		Expect(err).To(BeNil())
+		_, ok = core.GetAuthorizerFromContext(req.Context())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Cookies of API Gateway v2 events", func() {
+	cookieValues := func(req *http.Request) map[string]string {
+		values := map[string]string{}
+		for _, c := range req.Cookies() {
+			values[c.Name] = c.Value
+		}
+		return values
+	}
+
+	It("folds the cookies field into the Cookie header", func() {
+		event := benchEventV2()
+		delete(event.Headers, "cookie")
+		event.Cookies = []string{"session=abc", "theme=dark", "lang=en"}
+		req, err := (&core.RequestAccessorV2{}).EventToRequest(event)
+		Expect(err).To(BeNil())
+		Expect(req.Cookies()).To(HaveLen(3))
+		Expect(cookieValues(req)).To(Equal(map[string]string{"session": "abc", "theme": "dark", "lang": "en"}))
+	})
+
+	It("keeps the cookies of a cookie header next to the cookies field", func() {
+		event := benchEventV2()
+		event.Headers["cookie"] = "tracking=1; consent=yes"
+		event.Cookies = []string{"session=abc", "theme=dark"}
+		req, err := (&core.RequestAccessorV2{}).EventToRequest(event)
+		Expect(err).To(BeNil())
+		Expect(req.Cookies()).To(HaveLen(4))
+		Expect(cookieValues(req)).To(Equal(map[string]string{"session": "abc", "theme": "dark", "tracking": "1", "consent": "yes"}))
+	})
+})
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/rsingh25/tukashi-lib/util"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// RequestMetadata describes a request independently of the front end that delivered it.
+type RequestMetadata struct {
+	// Source is the front end of the request.
+	Source EventSource
+
+	// RequestID is the API Gateway request ID, or the X-Amzn-Trace-Id header behind ALB
+	// and VPC Lattice.
+	RequestID string
+
+	// SourceIP is the address of the client.
+	SourceIP string
+
+	// UserAgent is the User-Agent of the client.
+	UserAgent string
+
+	// Stage is the API Gateway stage, empty behind ALB.
+	Stage string
+
+	// DomainName is the host the client connected to.
+	DomainName string
+
+	// FunctionARN is the ARN the function was invoked with, empty outside Lambda.
+	FunctionARN string
+
+	// Claims holds the authorizer values as delivered by the front end: the claims of the
+	// X-Amzn-Oidc-Data header behind ALB, the Cognito claims or Lambda authorizer context
+	// of REST and WebSocket APIs, and the JWT claims or Lambda authorizer context of HTTP
+	// APIs. Other values are formatted as text. Behind VPC Lattice it holds the non empty
+	// fields of the caller identity, such as principal and sourceVpcArn. It is nil
+	// without authorizer.
+	//
+	// The X-Amzn-Oidc-Data claims are decoded without verifying the ES256 signature of
+	// the load balancer. They can only be trusted when the function is reachable through
+	// the ALB alone, as the header is sent as is by other callers.
+	Claims map[string]string
+}
+
+// RequestInfo returns the metadata of a request converted by any of the RequestAccessors,
+// so handlers do not depend on whether they run behind ALB or API Gateway.
+func RequestInfo(ctx context.Context) (RequestMetadata, bool) {
+	switch rc := ctx.Value(ctxKey{}).(type) {
+	case requestContextALB:
+		info := RequestMetadata{
+			Source:      EventSourceALB,
+			RequestID:   rc.traceID,
+			SourceIP:    rc.sourceIP,
+			UserAgent:   rc.userAgent,
+			DomainName:  rc.host,
+			FunctionARN: functionARN(rc.lambdaContext),
+		}
+		if rc.oidcData != "" {
+			if payload, err := util.DecodeJwtPayload(rc.oidcData); err == nil {
+				var claims map[string]interface{}
+				if json.Unmarshal(payload, &claims) == nil {
+					info.Claims = stringClaims(nil, claims)
+				}
+			}
+		}
+		return info, true
+
+	case requestContext:
+		gw := rc.gatewayProxyContext
+		return RequestMetadata{
+			Source:      EventSourceAPIGatewayV1,
+			RequestID:   gw.RequestID,
+			SourceIP:    gw.Identity.SourceIP,
+			UserAgent:   gw.Identity.UserAgent,
+			Stage:       gw.Stage,
+			DomainName:  gw.DomainName,
+			FunctionARN: functionARN(rc.lambdaContext),
+			Claims:      stringClaims(nil, gw.Authorizer),
+		}, true
+
+	case requestContextV2:
+		gw := rc.gatewayProxyContext
+		info := RequestMetadata{
+			Source:      EventSourceAPIGatewayV2,
+			RequestID:   gw.RequestID,
+			SourceIP:    gw.HTTP.SourceIP,
+			UserAgent:   gw.HTTP.UserAgent,
+			Stage:       gw.Stage,
+			DomainName:  gw.DomainName,
+			FunctionARN: functionARN(rc.lambdaContext),
+		}
+		if authorizer := gw.Authorizer; authorizer != nil {
+			if authorizer.JWT != nil {
+				for k, v := range authorizer.JWT.Claims {
+					if info.Claims == nil {
+						info.Claims = make(map[string]string)
+					}
+					info.Claims[k] = v
+				}
+			}
+			info.Claims = stringClaims(info.Claims, authorizer.Lambda)
+		}
+		return info, true
+
+	case requestContextWebsocket:
+		ws := rc.websocketContext
+		authorizer, _ := ws.Authorizer.(map[string]interface{})
+		return RequestMetadata{
+			Source:      EventSourceWebsocket,
+			RequestID:   ws.RequestID,
+			SourceIP:    ws.Identity.SourceIP,
+			UserAgent:   ws.Identity.UserAgent,
+			Stage:       ws.Stage,
+			DomainName:  ws.DomainName,
+			FunctionARN: functionARN(rc.lambdaContext),
+			Claims:      stringClaims(nil, authorizer),
+		}, true
+
+	case requestContextLattice:
+		return RequestMetadata{
+			Source:      rc.source,
+			RequestID:   rc.traceID,
+			SourceIP:    rc.sourceIP,
+			UserAgent:   rc.userAgent,
+			DomainName:  rc.host,
+			FunctionARN: functionARN(rc.lambdaContext),
+			Claims:      latticeClaims(rc.latticeContext.Identity),
+		}, true
+	}
+	return RequestMetadata{}, false
+}
+
+// latticeClaims returns the non empty fields of a Lattice caller identity, nil for V1
+// events.
+func latticeClaims(id VPCLatticeIdentity) map[string]string {
+	var claims map[string]string
+	for k, v := range map[string]string{
+		"sourceVpcArn":   id.SourceVpcARN,
+		"type":           id.Type,
+		"principal":      id.Principal,
+		"principalOrgID": id.PrincipalOrgID,
+		"sessionName":    id.SessionName,
+		"x509IssuerOu":   id.X509IssuerOu,
+		"x509SanDns":     id.X509SanDNS,
+		"x509SanNameCn":  id.X509SanNameCn,
+		"x509SanUri":     id.X509SanURI,
+		"x509SubjectCn":  id.X509SubjectCn,
+	} {
+		if v == "" {
+			continue
+		}
+		if claims == nil {
+			claims = make(map[string]string)
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func functionARN(lc *lambdacontext.LambdaContext) string {
+	if lc == nil {
+		return ""
+	}
+	return lc.InvokedFunctionArn
+}
+
+// stringClaims adds the values of an authorizer map to claims. The Cognito user pool
+// claims that REST APIs nest under "claims" are added at the top level.
+func stringClaims(claims map[string]string, values map[string]interface{}) map[string]string {
+	for k, v := range values {
+		if claims == nil {
+			claims = make(map[string]string)
+		}
+		switch v := v.(type) {
+		case nil:
+		case string:
+			claims[k] = v
+		case float64:
+			claims[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case map[string]interface{}:
+			if k == "claims" {
+				claims = stringClaims(claims, v)
+			}
+		default:
+			claims[k] = fmt.Sprint(v)
+		}
+	}
+	return claims
+}
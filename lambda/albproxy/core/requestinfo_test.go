@@ -0,0 +1,111 @@
+package core_test
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RequestInfo", func() {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		InvokedFunctionArn: "arn:aws:lambda:ap-south-1:000000000000:function:api",
+	})
+
+	It("describes ALB requests from their headers", func() {
+		oidc := "e30." + base64.StdEncoding.EncodeToString([]byte(`{"email":"a@example.com","exp":1700000000}`)) + ".sig"
+		accessor := core.RequestAccessorALB{}
+		req, err := accessor.EventToRequestWithContext(ctx, events.ALBTargetGroupRequest{
+			HTTPMethod: "GET",
+			Path:       "/",
+			Headers: map[string]string{
+				"host":              "app.example.com",
+				"user-agent":        "curl/8",
+				"x-forwarded-for":   "10.0.0.1, 192.0.2.1",
+				"x-amzn-trace-id":   "Root=1-abc",
+				"x-amzn-oidc-data":  oidc,
+				"x-forwarded-proto": "https",
+			},
+			RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+		})
+		Expect(err).To(BeNil())
+
+		info, ok := core.RequestInfo(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(info).To(Equal(core.RequestMetadata{
+			Source:      core.EventSourceALB,
+			RequestID:   "Root=1-abc",
+			SourceIP:    "192.0.2.1",
+			UserAgent:   "curl/8",
+			DomainName:  "app.example.com",
+			FunctionARN: "arn:aws:lambda:ap-south-1:000000000000:function:api",
+			Claims:      map[string]string{"email": "a@example.com", "exp": "1700000000"},
+		}))
+	})
+
+	It("describes REST API requests with Cognito claims", func() {
+		accessor := core.RequestAccessor{}
+		req, err := accessor.EventToRequestWithContext(ctx, events.APIGatewayProxyRequest{
+			HTTPMethod: "GET",
+			Path:       "/",
+			RequestContext: events.APIGatewayProxyRequestContext{
+				RequestID:  "r1",
+				Stage:      "prod",
+				DomainName: "api.example.com",
+				Identity:   events.APIGatewayRequestIdentity{SourceIP: "192.0.2.1", UserAgent: "curl/8"},
+				Authorizer: map[string]interface{}{"claims": map[string]interface{}{"email": "a@example.com"}, "principalId": "u1"},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		info, ok := core.RequestInfo(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(info).To(Equal(core.RequestMetadata{
+			Source:      core.EventSourceAPIGatewayV1,
+			RequestID:   "r1",
+			SourceIP:    "192.0.2.1",
+			UserAgent:   "curl/8",
+			Stage:       "prod",
+			DomainName:  "api.example.com",
+			FunctionARN: "arn:aws:lambda:ap-south-1:000000000000:function:api",
+			Claims:      map[string]string{"email": "a@example.com", "principalId": "u1"},
+		}))
+	})
+
+	It("describes HTTP API requests with JWT claims and Lambda authorizer values", func() {
+		accessor := core.RequestAccessorV2{}
+		req, err := accessor.EventToRequestWithContext(ctx, events.APIGatewayV2HTTPRequest{
+			RawPath: "/",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				RequestID:  "r2",
+				Stage:      "$default",
+				DomainName: "api.example.com",
+				HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", SourceIP: "192.0.2.1", UserAgent: "curl/8"},
+				Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+					JWT:    &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: map[string]string{"sub": "u1"}},
+					Lambda: map[string]interface{}{"role": "admin", "level": 2.0},
+				},
+			},
+		})
+		Expect(err).To(BeNil())
+
+		info, ok := core.RequestInfo(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(info.Source).To(Equal(core.EventSourceAPIGatewayV2))
+		Expect(info.RequestID).To(Equal("r2"))
+		Expect(info.SourceIP).To(Equal("192.0.2.1"))
+		Expect(info.Stage).To(Equal("$default"))
+		Expect(info.Claims).To(Equal(map[string]string{"sub": "u1", "role": "admin", "level": "2"}))
+	})
+
+	It("reports contexts without a converted request", func() {
+		_, ok := core.RequestInfo(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -3,17 +3,11 @@
 package core
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
 	"net/textproto"
-	"net/url"
-	"os"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -23,105 +17,94 @@ import (
 // RequestAccessorV2 objects give access to custom API Gateway properties
 // in the request.
 type RequestAccessorV2 struct {
-	stripBasePath string
+	basePath
 }
 
-// GetAPIGatewayContextV2 extracts the API Gateway context object from a
-// request's custom header.
+// GetAPIGatewayContextV2 returns the API Gateway context of a request converted by
+// ProxyEventToHTTPRequest or EventToRequestWithContext, or else the context stored
+// in the custom header by AddContextHeaders.
 // Returns a populated events.APIGatewayProxyRequestContext object from
 // the request.
 func (r *RequestAccessorV2) GetAPIGatewayContextV2(req *http.Request) (events.APIGatewayV2HTTPRequestContext, error) {
+	if apiGwContext, ok := GetAPIGatewayV2ContextFromContext(req.Context()); ok {
+		return apiGwContext, nil
+	}
 	if req.Header.Get(APIGwContextHeader) == "" {
 		return events.APIGatewayV2HTTPRequestContext{}, errors.New("No context header in request")
 	}
 	context := events.APIGatewayV2HTTPRequestContext{}
 	err := json.Unmarshal([]byte(req.Header.Get(APIGwContextHeader)), &context)
 	if err != nil {
-		log.Println("Erorr while unmarshalling context")
-		log.Println(err)
+		appLog.Error("Error while unmarshalling context", "err", err)
 		return events.APIGatewayV2HTTPRequestContext{}, err
 	}
 	return context, nil
 }
 
-// GetAPIGatewayStageVars extracts the API Gateway stage variables from a
-// request's custom header.
+// GetAPIGatewayStageVars returns the API Gateway stage variables of a request
+// converted by ProxyEventToHTTPRequest or EventToRequestWithContext, or else the
+// stage variables stored in the custom header by AddContextHeaders.
 // Returns a map[string]string of the stage variables and their values from
 // the request.
 func (r *RequestAccessorV2) GetAPIGatewayStageVars(req *http.Request) (map[string]string, error) {
+	if stageVars, ok := GetStageVarsFromContextV2(req.Context()); ok {
+		if stageVars == nil {
+			stageVars = make(map[string]string)
+		}
+		return stageVars, nil
+	}
 	stageVars := make(map[string]string)
 	if req.Header.Get(APIGwStageVarsHeader) == "" {
 		return stageVars, errors.New("No stage vars header in request")
 	}
 	err := json.Unmarshal([]byte(req.Header.Get(APIGwStageVarsHeader)), &stageVars)
 	if err != nil {
-		log.Println("Erorr while unmarshalling stage variables")
-		log.Println(err)
+		appLog.Error("Error while unmarshalling stage variables", "err", err)
 		return stageVars, err
 	}
 	return stageVars, nil
 }
 
-// StripBasePath instructs the RequestAccessor object that the given base
-// path should be removed from the request path before sending it to the
-// framework for routing. This is used when API Gateway is configured with
-// base path mappings in custom domain names.
-func (r *RequestAccessorV2) StripBasePath(basePath string) string {
-	if strings.Trim(basePath, " ") == "" {
-		r.stripBasePath = ""
-		return ""
+// AddContextHeaders serializes the stage variables and API Gateway context of req
+// into the APIGwStageVarsHeader and APIGwContextHeader headers, for handlers that
+// forward the request to services reading them.
+func (r *RequestAccessorV2) AddContextHeaders(req *http.Request) error {
+	v, ok := req.Context().Value(ctxKey{}).(requestContextV2)
+	if !ok {
+		return errors.New("No API Gateway context in request")
 	}
-
-	newBasePath := basePath
-	if !strings.HasPrefix(newBasePath, "/") {
-		newBasePath = "/" + newBasePath
-	}
-
-	if strings.HasSuffix(newBasePath, "/") {
-		newBasePath = newBasePath[:len(newBasePath)-1]
-	}
-
-	r.stripBasePath = newBasePath
-
-	return newBasePath
+	return addToHeaderV2(req, v.stageVars, v.gatewayProxyContext)
 }
 
 // ProxyEventToHTTPRequest converts an API Gateway proxy event into a http.Request object.
-// Returns the populated http request with additional two custom headers for the stage variables and API Gateway context.
-// To access these properties use the GetAPIGatewayStageVars and GetAPIGatewayContext method of the RequestAccessor object.
+// Returns the populated http request with the stage variables and API Gateway context as part of its context.
+// To access these properties use the GetAPIGatewayStageVars and GetAPIGatewayContextV2 method of the RequestAccessorV2 object.
 func (r *RequestAccessorV2) ProxyEventToHTTPRequest(req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
 	httpRequest, err := r.EventToRequest(req)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-	return addToHeaderV2(httpRequest, req)
+	return addToContextV2(httpRequest.Context(), httpRequest, req), nil
 }
 
 // EventToRequestWithContext converts an API Gateway proxy event and context into an http.Request object.
 // Returns the populated http request with lambda context, stage variables and APIGatewayProxyRequestContext as part of its context.
 // Access those using GetAPIGatewayContextFromContext, GetStageVarsFromContext and GetRuntimeContextFromContext functions in this package.
+// The context of a Lambda authorizer is available through GetAuthorizerFromContext.
 func (r *RequestAccessorV2) EventToRequestWithContext(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
-	httpRequest, err := r.EventToRequest(req)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-	return addToContextV2(ctx, httpRequest, req), nil
+	return eventToRequestWithContext(ctx, &r.basePath, MapperAPIGatewayV2{}, req)
 }
 
 // EventToRequest converts an API Gateway proxy event into an http.Request object.
 // Returns the populated request maintaining headers
 func (r *RequestAccessorV2) EventToRequest(req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
-	decodedBody := []byte(req.Body)
-	if req.IsBase64Encoded {
-		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
-		if err != nil {
-			return nil, err
-		}
-		decodedBody = base64Body
-	}
+	return eventToRequest(&r.basePath, MapperAPIGatewayV2{}, req)
+}
+
+// MapperAPIGatewayV2 is the EventMapper of API Gateway HTTP API (v2) and Function URL events.
+type MapperAPIGatewayV2 struct{}
 
+func (MapperAPIGatewayV2) MapEvent(req events.APIGatewayV2HTTPRequest) (HTTPEvent, error) {
 	path := req.RawPath
 
 	// if RawPath empty is, populate from request context
@@ -129,79 +112,80 @@ func (r *RequestAccessorV2) EventToRequest(req events.APIGatewayV2HTTPRequest) (
 		path = req.RequestContext.HTTP.Path
 	}
 
-	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
-		if strings.HasPrefix(path, r.stripBasePath) {
-			path = strings.Replace(path, r.stripBasePath, "", 1)
-		}
-	}
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-	serverAddress := "https://" + req.RequestContext.DomainName
-	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
-		serverAddress = customAddress
+	query := req.RawQueryString
+	if len(query) == 0 && len(req.QueryStringParameters) > 0 {
+		query = buildQuery(nil, req.QueryStringParameters, true)
 	}
-	path = serverAddress + path
 
-	if len(req.RawQueryString) > 0 {
-		path += "?" + req.RawQueryString
-	} else if len(req.QueryStringParameters) > 0 {
-		values := url.Values{}
-		for key, value := range req.QueryStringParameters {
-			values.Add(key, value)
-		}
-		path += "?" + values.Encode()
-	}
+	h := newHeaderValues(len(req.Headers)+1, len(req.Headers)+1)
 
-	httpRequest, err := http.NewRequest(
-		strings.ToUpper(req.RequestContext.HTTP.Method),
-		path,
-		bytes.NewReader(decodedBody),
-	)
-
-	if err != nil {
-		fmt.Printf("Could not convert request %s:%s to http.Request\n", req.RequestContext.HTTP.Method, req.RequestContext.HTTP.Path)
-		log.Println(err)
-		return nil, err
+	// API Gateway v2 and Function URLs deliver cookies in their own field. They are
+	// folded back into a single Cookie header as a browser would send them.
+	if len(req.Cookies) > 0 {
+		h.add("Cookie", strings.Join(req.Cookies, "; "))
 	}
 
-	httpRequest.RemoteAddr = req.RequestContext.HTTP.SourceIP
-
-	for _, cookie := range req.Cookies {
-		httpRequest.Header.Add("Cookie", cookie)
+	// repeated headers are delivered comma separated, except for the singleton
+	// headers whose single value may contain commas
+	for headerKey, headerValue := range req.Headers {
+		if singletonHeaders[textproto.CanonicalMIMEHeaderKey(headerKey)] {
+			h.add(headerKey, headerValue)
+			continue
+		}
+		if !strings.Contains(headerValue, ",") {
+			h.add(headerKey, strings.Trim(headerValue, " "))
+			continue
+		}
+		for _, val := range strings.Split(headerValue, ",") {
+			h.add(headerKey, strings.Trim(val, " "))
+		}
 	}
 
-	singletonHeaders, headers := splitSingletonHeaders(req.Headers)
-
-	for headerKey, headerValue := range singletonHeaders {
-		httpRequest.Header.Add(headerKey, headerValue)
-	}
+	return HTTPEvent{
+		Method:          req.RequestContext.HTTP.Method,
+		Path:            path,
+		RawQuery:        query,
+		Header:          h.header,
+		Body:            req.Body,
+		IsBase64Encoded: req.IsBase64Encoded,
+		Host:            req.RequestContext.DomainName,
+		RemoteAddr:      req.RequestContext.HTTP.SourceIP,
+		BasePath:        basePathV2(path, req),
+	}, nil
+}
 
-	for headerKey, headerValue := range headers {
-		for _, val := range strings.Split(headerValue, ",") {
-			httpRequest.Header.Add(headerKey, strings.Trim(val, " "))
+// basePathV2 returns the base path of an HTTP API event: the API mapping key or stage
+// in front of the route that matched, or else the stage of the default endpoint.
+func basePathV2(path string, req events.APIGatewayV2HTTPRequest) string {
+	if _, route, ok := strings.Cut(req.RouteKey, " "); ok {
+		if basePath, ok := routeBasePath(path, route, req.PathParameters); ok {
+			return basePath
 		}
 	}
+	if stage := req.RequestContext.Stage; stage != "" && stage != "$default" {
+		return "/" + stage
+	}
+	return ""
+}
 
-	httpRequest.RequestURI = httpRequest.URL.RequestURI()
-
-	return httpRequest, nil
+func (MapperAPIGatewayV2) AddToContext(ctx context.Context, req *http.Request, event events.APIGatewayV2HTTPRequest) *http.Request {
+	return addToContextV2(ctx, req, event)
 }
 
-func addToHeaderV2(req *http.Request, apiGwRequest events.APIGatewayV2HTTPRequest) (*http.Request, error) {
-	stageVars, err := json.Marshal(apiGwRequest.StageVariables)
+func addToHeaderV2(req *http.Request, stageVars map[string]string, apiGwContext events.APIGatewayV2HTTPRequestContext) error {
+	stageVarsJSON, err := json.Marshal(stageVars)
 	if err != nil {
-		log.Println("Could not marshal stage variables for custom header")
-		return nil, err
+		appLog.Error("Could not marshal stage variables for custom header", "err", err)
+		return err
 	}
-	req.Header.Add(APIGwStageVarsHeader, string(stageVars))
-	apiGwContext, err := json.Marshal(apiGwRequest.RequestContext)
+	req.Header.Set(APIGwStageVarsHeader, string(stageVarsJSON))
+	apiGwContextJSON, err := json.Marshal(apiGwContext)
 	if err != nil {
-		log.Println("Could not Marshal API GW context for custom header")
-		return req, err
+		appLog.Error("Could not Marshal API GW context for custom header", "err", err)
+		return err
 	}
-	req.Header.Add(APIGwContextHeader, string(apiGwContext))
-	return req, nil
+	req.Header.Set(APIGwContextHeader, string(apiGwContextJSON))
+	return nil
 }
 
 func addToContextV2(ctx context.Context, req *http.Request, apiGwRequest events.APIGatewayV2HTTPRequest) *http.Request {
@@ -235,23 +219,6 @@ type requestContextV2 struct {
 	stageVars           map[string]string
 }
 
-// splitSingletonHeaders splits the headers into single-value headers and other,
-// multi-value capable, headers.
-// Returns (single-value headers, multi-value-capable headers)
-func splitSingletonHeaders(headers map[string]string) (map[string]string, map[string]string) {
-	singletons := make(map[string]string)
-	multitons := make(map[string]string)
-	for headerKey, headerValue := range headers {
-		if ok := singletonHeaders[textproto.CanonicalMIMEHeaderKey(headerKey)]; ok {
-			singletons[headerKey] = headerValue
-		} else {
-			multitons[headerKey] = headerValue
-		}
-	}
-
-	return singletons, multitons
-}
-
 // singletonHeaders is a set of headers, that only accept a single
 // value which may be comma separated (according to RFC 7230)
 var singletonHeaders = map[string]bool{
@@ -3,11 +3,8 @@
 package core
 
 import (
-	"bytes"
-	"encoding/base64"
 	"errors"
 	"net/http"
-	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -20,73 +17,14 @@ const (
 // ProxyResponseWriter implements http.ResponseWriter and adds the method
 // necessary to return an events.APIGatewayProxyResponse object
 type ProxyResponseWriter struct {
-	headers   http.Header
-	body      bytes.Buffer
-	status    int
-	observers []chan<- bool
+	bufferedResponse
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
 // The object is initialized with an empty map of headers and a
 // status code of -1
 func NewProxyResponseWriter() *ProxyResponseWriter {
-	return &ProxyResponseWriter{
-		headers:   make(http.Header),
-		status:    defaultStatusCode,
-		observers: make([]chan<- bool, 0),
-	}
-
-}
-
-func (r *ProxyResponseWriter) CloseNotify() <-chan bool {
-	ch := make(chan bool, 1)
-
-	r.observers = append(r.observers, ch)
-
-	return ch
-}
-
-func (r *ProxyResponseWriter) notifyClosed() {
-	for _, v := range r.observers {
-		v <- true
-	}
-}
-
-// Header implementation from the http.ResponseWriter interface.
-func (r *ProxyResponseWriter) Header() http.Header {
-	return r.headers
-}
-
-// Write sets the response body in the object. If no status code
-// was set before with the WriteHeader method it sets the status
-// for the response to 200 OK.
-func (r *ProxyResponseWriter) Write(body []byte) (int, error) {
-	if r.status == defaultStatusCode {
-		r.status = http.StatusOK
-	}
-
-	// if the content type header is not set when we write the body we try to
-	// detect one and set it by default. If the content type cannot be detected
-	// it is automatically set to "application/octet-stream" by the
-	// DetectContentType method
-	if r.Header().Get(contentTypeHeaderKey) == "" {
-		r.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
-	}
-
-	return (&r.body).Write(body)
-}
-
-// WriteHeader sets a status code for the response. This method is used
-// for error responses.
-func (r *ProxyResponseWriter) WriteHeader(status int) {
-	r.status = status
-}
-
-
-// Flush implements the Flusher interface which is called by 
-// some implementers. This is intentionally a no-op
-func (r *ProxyResponseWriter) Flush() {
-	//no-op
+	return &ProxyResponseWriter{bufferedResponse: newBufferedResponse()}
 }
 
 // GetProxyResponse converts the data passed to the response writer into
@@ -95,27 +33,26 @@ func (r *ProxyResponseWriter) Flush() {
 // has no headers or an invalid status code returns an error.
 func (r *ProxyResponseWriter) GetProxyResponse() (events.APIGatewayProxyResponse, error) {
 	r.notifyClosed()
+	promoteTrailers(r.headers)
 
 	if r.status == defaultStatusCode {
 		return events.APIGatewayProxyResponse{}, errors.New("Status code not set on response")
 	}
 
-	var output string
-	isBase64 := false
+	output, isBase64 := r.encodeBody()
 
-	bb := (&r.body).Bytes()
-
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
-		output = base64.StdEncoding.EncodeToString(bb)
-		isBase64 = true
-	}
-
-	return events.APIGatewayProxyResponse{
+	resp := events.APIGatewayProxyResponse{
 		StatusCode:        r.status,
 		MultiValueHeaders: http.Header(r.headers),
 		Body:              output,
 		IsBase64Encoded:   isBase64,
-	}, nil
+	}
+
+	limit := r.payload.maxSize(MaxResponseSizeAPIGateway)
+	if exceeded, size := exceedsPayloadLimit(resp, len(output), r.headers, limit); exceeded {
+		appLog.Error("Response exceeds the API Gateway payload limit", "status", r.status, "size", size, "limit", limit)
+		return ResponseTooLarge(size, limit), nil
+	}
+
+	return resp, nil
 }
@@ -3,11 +3,9 @@
 package core
 
 import (
-	"bytes"
-	"encoding/base64"
 	"errors"
 	"net/http"
-	"unicode/utf8"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -15,68 +13,22 @@ import (
 // ProxyResponseWriter implements http.ResponseWriter and adds the method
 // necessary to return an events.ALBTargetGroupResponse object
 type ProxyResponseWriterALB struct {
-	headers    http.Header
-	body       bytes.Buffer
-	status     int
-	statusText string
-	observers  []chan<- bool
+	bufferedResponse
+	singleValueHeaders bool
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
 // The object is initialized with an empty map of headers and a
 // status code of -1
 func NewProxyResponseWriterALB() *ProxyResponseWriterALB {
-	return &ProxyResponseWriterALB{
-		headers:    make(http.Header),
-		status:     defaultStatusCode,
-		statusText: http.StatusText(defaultStatusCode),
-		observers:  make([]chan<- bool, 0),
-	}
-
-}
-
-func (r *ProxyResponseWriterALB) CloseNotify() <-chan bool {
-	ch := make(chan bool, 1)
-
-	r.observers = append(r.observers, ch)
-
-	return ch
+	return &ProxyResponseWriterALB{bufferedResponse: newBufferedResponse()}
 }
 
-func (r *ProxyResponseWriterALB) notifyClosed() {
-	for _, v := range r.observers {
-		v <- true
-	}
-}
-
-// Header implementation from the http.ResponseWriter interface.
-func (r *ProxyResponseWriterALB) Header() http.Header {
-	return r.headers
-}
-
-// Write sets the response body in the object. If no status code
-// was set before with the WriteHeader method it sets the status
-// for the response to 200 OK.
-func (r *ProxyResponseWriterALB) Write(body []byte) (int, error) {
-	if r.status == defaultStatusCode {
-		r.status = http.StatusOK
-	}
-
-	// if the content type header is not set when we write the body we try to
-	// detect one and set it by default. If the content type cannot be detected
-	// it is automatically set to "application/octet-stream" by the
-	// DetectContentType method
-	if r.Header().Get(contentTypeHeaderKey) == "" {
-		r.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
-	}
-
-	return (&r.body).Write(body)
-}
-
-// WriteHeader sets a status code for the response. This method is used
-// for error responses.
-func (r *ProxyResponseWriterALB) WriteHeader(status int) {
-	r.status = status
+// SetMultiValueHeaders selects whether the response uses MultiValueHeaders, the
+// default, or Headers. It must match the lambda.multi_value_headers.enabled
+// attribute of the target group, see IsMultiValueALB.
+func (r *ProxyResponseWriterALB) SetMultiValueHeaders(enabled bool) {
+	r.singleValueHeaders = !enabled
 }
 
 // GetProxyResponse converts the data passed to the response writer into
@@ -85,28 +37,88 @@ func (r *ProxyResponseWriterALB) WriteHeader(status int) {
 // has no headers or an invalid status code returns an error.
 func (r *ProxyResponseWriterALB) GetProxyResponse() (events.ALBTargetGroupResponse, error) {
 	r.notifyClosed()
+	promoteTrailers(r.headers)
 
 	if r.status == defaultStatusCode {
 		return events.ALBTargetGroupResponse{}, errors.New("status code not set on response")
 	}
 
-	var output string
-	isBase64 := false
-
-	bb := (&r.body).Bytes()
+	output, isBase64 := r.encodeBody()
 
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
-		output = base64.StdEncoding.EncodeToString(bb)
-		isBase64 = true
-	}
-
-	return events.ALBTargetGroupResponse{
+	resp := events.ALBTargetGroupResponse{
 		StatusCode:        r.status,
 		StatusDescription: http.StatusText(r.status),
 		MultiValueHeaders: http.Header(r.headers),
 		Body:              output,
 		IsBase64Encoded:   isBase64,
-	}, nil
+	}
+
+	limit := r.payload.maxSize(MaxResponseSizeALB)
+	if exceeded, size := exceedsPayloadLimit(resp, len(output), r.headers, limit); exceeded {
+		appLog.Error("Response exceeds the ALB payload limit", "status", r.status, "size", size, "limit", limit)
+		resp = ResponseTooLargeALB(size, limit)
+	}
+
+	if r.singleValueHeaders {
+		return SingleValueHeadersALB(resp), nil
+	}
+	return resp, nil
+}
+
+// SingleValueHeadersALB moves the MultiValueHeaders of resp into Headers, for
+// target groups without multi value headers. Repeated values are joined with
+// commas, except Set-Cookie which cannot be joined: every cookie is sent under
+// a differently cased Set-Cookie key, which clients treat as the same header.
+func SingleValueHeadersALB(resp events.ALBTargetGroupResponse) events.ALBTargetGroupResponse {
+	if resp.MultiValueHeaders == nil {
+		return resp
+	}
+
+	resp.Headers = singleValueHeaders(resp.MultiValueHeaders)
+	resp.MultiValueHeaders = nil
+	return resp
+}
+
+// singleValueHeaders joins repeated header values with commas, except Set-Cookie
+// which cannot be joined and is spread over differently cased keys.
+func singleValueHeaders(multi http.Header) map[string]string {
+	headers := make(map[string]string, len(multi))
+	var cookies []string
+	for headerKey, headerValue := range multi {
+		if len(headerValue) == 0 {
+			continue
+		}
+		if strings.EqualFold("set-cookie", headerKey) {
+			cookies = append(cookies, headerValue...)
+			continue
+		}
+		headers[headerKey] = strings.Join(headerValue, ",")
+	}
+	for i, cookie := range cookies {
+		if i == maxSetCookieKeys {
+			appLog.Warn("Too many cookies for single value header mode, dropping the rest", "cookies", len(cookies), "sent", i)
+			break
+		}
+		headers[setCookieKey(i)] = cookie
+	}
+	return headers
+}
+
+// maxSetCookieKeys is the number of distinct casings of Set-Cookie.
+const maxSetCookieKeys = 1 << 9
+
+// setCookieKey returns the i-th casing of Set-Cookie, toggling the case of the
+// letters selected by the bits of i. The first one is the canonical key.
+func setCookieKey(i int) string {
+	key := []byte("Set-Cookie")
+	for pos, bit := 0, 0; pos < len(key); pos++ {
+		if key[pos] == '-' {
+			continue
+		}
+		if i&(1<<bit) != 0 {
+			key[pos] ^= 'a' - 'A'
+		}
+		bit++
+	}
+	return string(key)
 }
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ProxyResponseWriterLattice implements http.ResponseWriter and adds the method
+// necessary to return a VPCLatticeResponse object
+type ProxyResponseWriterLattice struct {
+	bufferedResponse
+}
+
+// NewProxyResponseWriterLattice returns a new ProxyResponseWriterLattice object.
+// The object is initialized with an empty map of headers and a
+// status code of -1
+func NewProxyResponseWriterLattice() *ProxyResponseWriterLattice {
+	return &ProxyResponseWriterLattice{bufferedResponse: newBufferedResponse()}
+}
+
+// GetProxyResponse converts the data passed to the response writer into
+// a VPCLatticeResponse object. Lattice only accepts single value headers, see
+// SingleValueHeadersALB for how repeated values are sent.
+// Returns a populated proxy response object. If the response is invalid, for example
+// has no headers or an invalid status code returns an error.
+func (r *ProxyResponseWriterLattice) GetProxyResponse() (VPCLatticeResponse, error) {
+	r.notifyClosed()
+	promoteTrailers(r.headers)
+
+	if r.status == defaultStatusCode {
+		return VPCLatticeResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64 := r.encodeBody()
+
+	resp := VPCLatticeResponse{
+		StatusCode:        r.status,
+		StatusDescription: latticeStatusDescription(r.status),
+		Headers:           singleValueHeaders(r.headers),
+		Body:              output,
+		IsBase64Encoded:   isBase64,
+	}
+
+	limit := r.payload.maxSize(MaxResponseSizeLattice)
+	if exceeded, size := exceedsPayloadLimit(resp, len(output), r.headers, limit); exceeded {
+		appLog.Error("Response exceeds the VPC Lattice payload limit", "status", r.status, "size", size, "limit", limit)
+		return ResponseTooLargeLattice(size, limit), nil
+	}
+
+	return resp, nil
+}
+
+// latticeStatusDescription formats a status like "200 OK".
+func latticeStatusDescription(status int) string {
+	return strconv.Itoa(status) + " " + http.StatusText(status)
+}
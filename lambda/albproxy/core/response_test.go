@@ -0,0 +1,316 @@
+package core_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// proxyResponse is the front end independent view of a proxy response.
+type proxyResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// writerCase creates a proxy response writer and reads back its response.
+type writerCase struct {
+	name   string
+	writer func() (http.ResponseWriter, func() (proxyResponse, error))
+}
+
+func singleValues(headers map[string]string) http.Header {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+var writerCases = []writerCase{
+	{name: "API Gateway v1", writer: func() (http.ResponseWriter, func() (proxyResponse, error)) {
+		w := core.NewProxyResponseWriter()
+		w.SetPayloadOptions(core.PayloadOptions{DisableCompression: true})
+		return w, func() (proxyResponse, error) {
+			resp, err := w.GetProxyResponse()
+			return proxyResponse{resp.StatusCode, resp.MultiValueHeaders, resp.Body}, err
+		}
+	}},
+	{name: "API Gateway v2", writer: func() (http.ResponseWriter, func() (proxyResponse, error)) {
+		w := core.NewProxyResponseWriterV2()
+		w.SetPayloadOptions(core.PayloadOptions{DisableCompression: true})
+		return w, func() (proxyResponse, error) {
+			resp, err := w.GetProxyResponse()
+			return proxyResponse{resp.StatusCode, singleValues(resp.Headers), resp.Body}, err
+		}
+	}},
+	{name: "ALB", writer: func() (http.ResponseWriter, func() (proxyResponse, error)) {
+		w := core.NewProxyResponseWriterALB()
+		w.SetPayloadOptions(core.PayloadOptions{DisableCompression: true})
+		return w, func() (proxyResponse, error) {
+			resp, err := w.GetProxyResponse()
+			return proxyResponse{resp.StatusCode, resp.MultiValueHeaders, resp.Body}, err
+		}
+	}},
+	{name: "VPC Lattice", writer: func() (http.ResponseWriter, func() (proxyResponse, error)) {
+		w := core.NewProxyResponseWriterLattice()
+		w.SetPayloadOptions(core.PayloadOptions{DisableCompression: true})
+		return w, func() (proxyResponse, error) {
+			resp, err := w.GetProxyResponse()
+			return proxyResponse{resp.StatusCode, singleValues(resp.Headers), resp.Body}, err
+		}
+	}},
+}
+
+var _ = Describe("Proxy response writers", func() {
+	for _, wc := range writerCases {
+		Describe(wc.name, func() {
+			It("keeps the first status and ignores 1xx statuses", func() {
+				w, result := wc.writer()
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("created"))
+
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusCreated))
+			})
+
+			It("ignores WriteHeader after Write", func() {
+				w, result := wc.writer()
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusNotFound)
+
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusOK))
+			})
+
+			It("implements io.StringWriter and io.ReaderFrom", func() {
+				w, result := wc.writer()
+				n, err := w.(io.StringWriter).WriteString("hello ")
+				Expect(err).To(BeNil())
+				Expect(n).To(Equal(6))
+				m, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("world"))
+				Expect(err).To(BeNil())
+				Expect(m).To(Equal(int64(5)))
+
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusOK))
+				Expect(resp.body).To(Equal("hello world"))
+			})
+
+			It("sends trailers as headers", func() {
+				w, result := wc.writer()
+				w.Header().Set("Trailer", "X-Checksum")
+				w.Write([]byte("body"))
+				w.Header().Set("X-Checksum", "abc")
+				w.Header().Set(http.TrailerPrefix+"X-Elapsed", "3ms")
+
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.header.Get("X-Checksum")).To(Equal("abc"))
+				Expect(resp.header.Get("X-Elapsed")).To(Equal("3ms"))
+				Expect(resp.header).ToNot(HaveKey("Trailer"))
+				Expect(resp.header).ToNot(HaveKey(http.TrailerPrefix + "X-Elapsed"))
+			})
+
+			It("starts every response with an empty body", func() {
+				for _, body := range []string{"first response", "second"} {
+					w, result := wc.writer()
+					w.Write([]byte(body))
+					resp, err := result()
+					Expect(err).To(BeNil())
+					Expect(resp.body).To(Equal(body))
+				}
+			})
+
+			It("supports http.ResponseController", func() {
+				w, result := wc.writer()
+				rc := http.NewResponseController(w)
+				Expect(rc.SetWriteDeadline(time.Now().Add(time.Second))).To(MatchError(http.ErrNotSupported))
+				Expect(rc.SetReadDeadline(time.Time{})).To(MatchError(http.ErrNotSupported))
+				Expect(rc.EnableFullDuplex()).To(Succeed())
+				w.Write([]byte("chunk"))
+				Expect(rc.Flush()).To(Succeed())
+
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.body).To(Equal("chunk"))
+			})
+
+			It("serves files with http.FileServer", func() {
+				fs := http.FileServer(http.FS(fstest.MapFS{
+					"index.txt": {Data: []byte("0123456789"), ModTime: time.Unix(1700000000, 0)},
+				}))
+
+				w, result := wc.writer()
+				fs.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.txt", nil))
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusOK))
+				Expect(resp.body).To(Equal("0123456789"))
+				Expect(resp.header.Get("Last-Modified")).ToNot(BeEmpty())
+
+				w, result = wc.writer()
+				req := httptest.NewRequest(http.MethodGet, "/index.txt", nil)
+				req.Header.Set("Range", "bytes=2-4")
+				fs.ServeHTTP(w, req)
+				resp, err = result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusPartialContent))
+				Expect(resp.body).To(Equal("234"))
+				Expect(resp.header.Get("Content-Range")).To(Equal("bytes 2-4/10"))
+			})
+
+			It("relays responses of httputil.ReverseProxy", func() {
+				backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Trailer", "X-Checksum")
+					w.Header().Set("Content-Type", "text/plain")
+					w.WriteHeader(http.StatusAccepted)
+					w.Write([]byte("from backend " + r.URL.Path))
+					w.Header().Set("X-Checksum", "abc")
+				}))
+				defer backend.Close()
+				target, _ := url.Parse(backend.URL)
+				proxy := httputil.NewSingleHostReverseProxy(target)
+				proxy.FlushInterval = -1
+
+				w, result := wc.writer()
+				proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+				resp, err := result()
+				Expect(err).To(BeNil())
+				Expect(resp.status).To(Equal(http.StatusAccepted))
+				Expect(resp.body).To(Equal("from backend /orders"))
+				Expect(resp.header.Get("Content-Type")).To(Equal("text/plain"))
+				Expect(resp.header.Get("X-Checksum")).To(Equal("abc"))
+			})
+		})
+	}
+})
+
+var _ = Describe("ProxyResponseWriterStream", func() {
+	It("keeps the first status, ignores 1xx statuses and promotes trailers", func() {
+		w := core.NewProxyResponseWriterStream()
+		w.Header().Set(http.TrailerPrefix+"X-Elapsed", "3ms")
+		w.WriteHeader(http.StatusProcessing)
+		w.WriteHeader(http.StatusAccepted)
+		w.WriteHeader(http.StatusTeapot)
+
+		go func() {
+			io.WriteString(w, "hello ")
+			w.ReadFrom(strings.NewReader("stream"))
+			w.Close()
+		}()
+		resp := w.GetStreamingResponse()
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resp.Headers).To(HaveKeyWithValue("X-Elapsed", "3ms"))
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(Equal("hello stream"))
+	})
+
+	It("sniffs the content type of the first chunk only", func() {
+		w := core.NewProxyResponseWriterStream()
+		go func() {
+			io.WriteString(w, "<html>")
+			w.Header().Del("Content-Type")
+			io.WriteString(w, "</html>")
+			w.Close()
+		}()
+		resp := w.GetStreamingResponse()
+		Expect(resp.Headers).To(HaveKeyWithValue("Content-Type", "text/html; charset=utf-8"))
+		io.ReadAll(resp.Body)
+		Expect(w.Header()).ToNot(HaveKey("Content-Type"))
+	})
+
+	It("leaves the content type unset when sniffing is disabled", func() {
+		w := core.NewProxyResponseWriterStream()
+		w.SetPayloadOptions(core.PayloadOptions{DisableContentSniffing: true})
+		go func() {
+			io.WriteString(w, "<html></html>")
+			w.Close()
+		}()
+		resp := w.GetStreamingResponse()
+		Expect(resp.Headers).ToNot(HaveKey("Content-Type"))
+		io.ReadAll(resp.Body)
+	})
+
+	It("does not support deadlines through http.ResponseController", func() {
+		w := core.NewProxyResponseWriterStream()
+		rc := http.NewResponseController(w)
+		Expect(rc.SetWriteDeadline(time.Now().Add(time.Second))).To(MatchError(http.ErrNotSupported))
+		Expect(rc.SetReadDeadline(time.Time{})).To(MatchError(http.ErrNotSupported))
+		Expect(rc.EnableFullDuplex()).To(Succeed())
+	})
+
+	It("reports flush errors through http.ResponseController", func() {
+		w := core.NewProxyResponseWriterStream()
+		go func() {
+			resp := w.GetStreamingResponse()
+			resp.Body.(io.Closer).Close()
+		}()
+		w.Write([]byte("gone"))
+		Expect(http.NewResponseController(w).Flush()).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("Set-Cookie", func() {
+	setCookies := func(w http.ResponseWriter) {
+		w.Header().Add("Set-Cookie", "session=abc; HttpOnly")
+		w.Header().Add("Set-Cookie", "theme=dark")
+		w.Header().Add("Set-Cookie", "lang=en; Path=/")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+	cookies := []string{"session=abc; HttpOnly", "theme=dark", "lang=en; Path=/"}
+
+	It("keeps every cookie in ALB multi value headers", func() {
+		w := core.NewProxyResponseWriterALB()
+		setCookies(w)
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.MultiValueHeaders["Set-Cookie"]).To(Equal(cookies))
+	})
+
+	It("sends every cookie under its own key in ALB single value headers", func() {
+		w := core.NewProxyResponseWriterALB()
+		w.SetMultiValueHeaders(false)
+		setCookies(w)
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.MultiValueHeaders).To(BeNil())
+		Expect(resp.Headers).To(HaveKeyWithValue("Set-Cookie", "session=abc; HttpOnly"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Cache-Control", "no-store"))
+
+		var sent []string
+		for k, v := range resp.Headers {
+			if strings.EqualFold(k, "set-cookie") {
+				sent = append(sent, v)
+			}
+		}
+		Expect(sent).To(ConsistOf(cookies))
+		Expect(resp.Headers).To(HaveLen(len(cookies) + 1))
+	})
+
+	It("moves every cookie to Cookies in API Gateway v2 responses", func() {
+		w := core.NewProxyResponseWriterV2()
+		setCookies(w)
+		resp, err := w.GetProxyResponse()
+		Expect(err).To(BeNil())
+		Expect(resp.Cookies).To(Equal(cookies))
+		Expect(resp.Headers).ToNot(HaveKey("Set-Cookie"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Cache-Control", "no-store"))
+	})
+})
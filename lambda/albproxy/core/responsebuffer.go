@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// bufferedResponse is the http.ResponseWriter shared by the proxy response writers,
+// which buffer the complete response until the handler returns. It follows net/http
+// where handlers can tell the difference: only the first status is kept, interim 1xx
+// statuses are dropped, and io.ReaderFrom, io.StringWriter and the methods used by
+// http.ResponseController are implemented. Read and write deadlines are not
+// supported, see responseControl.
+//
+// The body is written to a pooled buffer, which GetProxyResponse releases once the
+// response is generated; like with net/http, the writer must not be used after the
+// handler returned.
+type bufferedResponse struct {
+	responseControl
+	headers   http.Header
+	body      *bytes.Buffer
+	status    int
+	observers []chan<- bool
+	payload   PayloadOptions
+}
+
+func newBufferedResponse() bufferedResponse {
+	return bufferedResponse{
+		headers:   make(http.Header),
+		status:    defaultStatusCode,
+		observers: make([]chan<- bool, 0),
+	}
+}
+
+func (r *bufferedResponse) CloseNotify() <-chan bool {
+	ch := make(chan bool, 1)
+
+	r.observers = append(r.observers, ch)
+
+	return ch
+}
+
+func (r *bufferedResponse) notifyClosed() {
+	for _, v := range r.observers {
+		v <- true
+	}
+}
+
+// Header implementation from the http.ResponseWriter interface.
+func (r *bufferedResponse) Header() http.Header {
+	return r.headers
+}
+
+// SetPayloadOptions configures body compression and the response payload limit.
+func (r *bufferedResponse) SetPayloadOptions(opts PayloadOptions) {
+	r.payload = opts
+}
+
+// Write sets the response body in the object. If no status code
+// was set before with the WriteHeader method it sets the status
+// for the response to 200 OK. The content type is detected from the
+// complete body when the response is generated.
+func (r *bufferedResponse) Write(body []byte) (int, error) {
+	r.writeBody()
+	return r.body.Write(body)
+}
+
+// WriteString implements io.StringWriter, like Write without copying s.
+func (r *bufferedResponse) WriteString(s string) (int, error) {
+	r.writeBody()
+	return r.body.WriteString(s)
+}
+
+// ReadFrom implements io.ReaderFrom, which io.Copy uses to read the body of files
+// served by http.FileServer and of proxied responses straight into the buffer.
+func (r *bufferedResponse) ReadFrom(src io.Reader) (int64, error) {
+	r.writeBody()
+	return r.body.ReadFrom(src)
+}
+
+func (r *bufferedResponse) writeBody() {
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+	if r.body == nil {
+		r.body = getBuffer()
+	}
+}
+
+// encodeBody returns the body as encoded by encodeBody of the payload options and
+// releases the body buffer.
+func (r *bufferedResponse) encodeBody() (string, bool) {
+	var bb []byte
+	if r.body != nil {
+		bb = r.body.Bytes()
+		defer func() {
+			putBuffer(r.body)
+			r.body = nil
+		}()
+	}
+	return r.payload.encodeBody(r.headers, bb)
+}
+
+// WriteHeader sets a status code for the response. Like net/http, informational
+// statuses other than 101 are not final; they are dropped as the front ends cannot
+// send them. Calls after the status was set, explicitly or by Write, are ignored.
+func (r *bufferedResponse) WriteHeader(status int) {
+	if isInterimStatus(status) {
+		return
+	}
+	if r.status != defaultStatusCode {
+		appLog.Warn("Superfluous WriteHeader call", "status", r.status, "ignored", status)
+		return
+	}
+	r.status = status
+}
+
+// Flush implements the Flusher interface which is called by
+// some implementers. This is intentionally a no-op
+func (r *bufferedResponse) Flush() {
+	//no-op
+}
+
+// FlushError is the http.ResponseController variant of Flush.
+func (r *bufferedResponse) FlushError() error {
+	return nil
+}
+
+// responseControl implements the http.ResponseController methods that behave the same
+// for the buffered and the streaming proxy response writers.
+type responseControl struct{}
+
+// SetReadDeadline returns http.ErrNotSupported. The request body is already in memory
+// and the adapters enforce the invocation deadline.
+func (responseControl) SetReadDeadline(deadline time.Time) error {
+	return http.ErrNotSupported
+}
+
+// SetWriteDeadline returns http.ErrNotSupported, see SetReadDeadline.
+func (responseControl) SetWriteDeadline(deadline time.Time) error {
+	return http.ErrNotSupported
+}
+
+// EnableFullDuplex is accepted, as the request body can always be read after writing;
+// it is in memory.
+func (responseControl) EnableFullDuplex() error {
+	return nil
+}
+
+// promoteTrailers turns trailers into ordinary headers, as no front end supports
+// them: values of headers announced in the Trailer header are already in the header
+// map, and those set with the http.TrailerPrefix are moved to their name.
+func promoteTrailers(h http.Header) {
+	for k, v := range h {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			delete(h, k)
+			name = http.CanonicalHeaderKey(name)
+			h[name] = append(h[name], v...)
+		}
+	}
+	h.Del("Trailer")
+}
+
+// isInterimStatus reports whether status is an informational status that net/http
+// does not treat as the final one.
+func isInterimStatus(status int) bool {
+	return status >= 100 && status <= 199 && status != http.StatusSwitchingProtocols
+}
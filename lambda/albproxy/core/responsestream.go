@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const defaultStreamBufferSize = 4096
+
+// ProxyResponseWriterStream implements http.ResponseWriter for Lambda Function URLs
+// configured with the RESPONSE_STREAM invoke mode. Unlike the other proxy response
+// writers the body is not buffered until the handler returns: it is written to a pipe
+// read by the Lambda runtime, and Flush pushes the pending bytes to the client.
+// Headers are committed on the first Write or Flush, so trailers are only sent when
+// they are set before, as ordinary headers.
+type ProxyResponseWriterStream struct {
+	responseControl
+	mu        sync.Mutex
+	headers   http.Header
+	status    int
+	pr        *io.PipeReader
+	pw        *io.PipeWriter
+	buf       *bufio.Writer
+	committed chan struct{}
+	resp      *events.LambdaFunctionURLStreamingResponse
+	payload   PayloadOptions
+}
+
+// NewProxyResponseWriterStream returns a new ProxyResponseWriterStream object.
+// The object is initialized with an empty map of headers and a
+// status code of -1
+func NewProxyResponseWriterStream() *ProxyResponseWriterStream {
+	pr, pw := io.Pipe()
+	return &ProxyResponseWriterStream{
+		headers:   make(http.Header),
+		status:    defaultStatusCode,
+		pr:        pr,
+		pw:        pw,
+		buf:       bufio.NewWriterSize(pw, defaultStreamBufferSize),
+		committed: make(chan struct{}),
+	}
+}
+
+// Header implementation from the http.ResponseWriter interface.
+func (r *ProxyResponseWriterStream) Header() http.Header {
+	return r.headers
+}
+
+// SetPayloadOptions configures content sniffing. The other options do not apply
+// to streamed bodies, which are neither compressed nor base64 encoded and are not
+// held against a payload limit.
+func (r *ProxyResponseWriterStream) SetPayloadOptions(opts PayloadOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payload = opts
+}
+
+// Write sends body bytes to the stream. If no status code was set before
+// with the WriteHeader method it sets the status for the response to 200 OK.
+// The first call commits the status code and headers.
+func (r *ProxyResponseWriterStream) Write(body []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+
+	// if the content type header is not set when we write the first chunk we try
+	// to detect one and set it by default, as the buffered writers do. Once the
+	// headers are committed a detected type would never reach the client.
+	if r.resp == nil && !r.payload.DisableContentSniffing && r.Header().Get(contentTypeHeaderKey) == "" {
+		r.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
+	}
+
+	r.commit()
+	return r.buf.Write(body)
+}
+
+// WriteString implements io.StringWriter.
+func (r *ProxyResponseWriterStream) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+// ReadFrom implements io.ReaderFrom. Once the content type is known the body is
+// copied to the stream without an intermediate buffer.
+func (r *ProxyResponseWriterStream) ReadFrom(src io.Reader) (int64, error) {
+	r.mu.Lock()
+	if r.resp == nil && !r.payload.DisableContentSniffing && r.headers.Get(contentTypeHeaderKey) == "" {
+		r.mu.Unlock()
+		// the first bytes are needed to detect the content type
+		return io.Copy(struct{ io.Writer }{r}, src)
+	}
+	defer r.mu.Unlock()
+
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+	r.commit()
+	return r.buf.ReadFrom(src)
+}
+
+// WriteHeader sets a status code for the response. Headers are sent to the
+// client on the first Write or Flush. Like net/http, informational statuses other
+// than 101 are dropped and only the first final status is kept.
+func (r *ProxyResponseWriterStream) WriteHeader(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if isInterimStatus(status) {
+		return
+	}
+	if r.status != defaultStatusCode {
+		appLog.Warn("Superfluous WriteHeader call", "status", r.status, "ignored", status)
+		return
+	}
+	r.status = status
+}
+
+// Flush commits the headers and pushes any buffered body bytes to the client.
+// It blocks until the runtime has consumed them.
+func (r *ProxyResponseWriterStream) Flush() {
+	if err := r.FlushError(); err != nil {
+		appLog.Debug("Could not flush response stream", "err", err)
+	}
+}
+
+// FlushError is the http.ResponseController variant of Flush, which reports
+// a stream closed by the runtime.
+func (r *ProxyResponseWriterStream) FlushError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+	r.commit()
+	return r.buf.Flush()
+}
+
+// GetStreamingResponse returns the streaming response whose body is read from
+// this writer. It blocks until the status code and headers are committed.
+func (r *ProxyResponseWriterStream) GetStreamingResponse() *events.LambdaFunctionURLStreamingResponse {
+	// resp is never modified after committed is closed, and a handler blocked
+	// on the pipe holds the lock, so it is read without taking r.mu.
+	<-r.committed
+	return r.resp
+}
+
+// Close flushes any remaining bytes and ends the stream. A handler that never
+// wrote anything gets its status code and headers committed with an empty body.
+func (r *ProxyResponseWriterStream) Close() error {
+	return r.CloseWithError(nil)
+}
+
+// CloseWithError ends the stream. A non nil error is reported to the runtime,
+// which aborts the response instead of completing it. If nothing was committed
+// yet the response status becomes 500.
+func (r *ProxyResponseWriterStream) CloseWithError(err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.resp == nil && err != nil {
+		r.status = http.StatusInternalServerError
+	}
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+	r.commit()
+	if err != nil {
+		return r.pw.CloseWithError(err)
+	}
+	if ferr := r.buf.Flush(); ferr != nil {
+		r.pw.CloseWithError(ferr)
+		return ferr
+	}
+	return r.pw.Close()
+}
+
+// commit snapshots the status code and headers into the streaming response.
+// The caller must hold r.mu.
+func (r *ProxyResponseWriterStream) commit() {
+	if r.resp != nil {
+		return
+	}
+	promoteTrailers(r.headers)
+
+	headers := make(map[string]string)
+	cookies := make([]string, 0)
+
+	for headerKey, headerValue := range r.headers {
+		if strings.EqualFold("set-cookie", headerKey) {
+			cookies = append(cookies, headerValue...)
+			continue
+		}
+		headers[headerKey] = strings.Join(headerValue, ",")
+	}
+
+	r.resp = &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: r.status,
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       r.pr,
+	}
+	close(r.committed)
+}
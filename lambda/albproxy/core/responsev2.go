@@ -3,12 +3,9 @@
 package core
 
 import (
-	"bytes"
-	"encoding/base64"
 	"errors"
 	"net/http"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -16,66 +13,14 @@ import (
 // ProxyResponseWriterV2 implements http.ResponseWriter and adds the method
 // necessary to return an events.APIGatewayProxyResponse object
 type ProxyResponseWriterV2 struct {
-	headers   http.Header
-	body      bytes.Buffer
-	status    int
-	observers []chan<- bool
+	bufferedResponse
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
 // The object is initialized with an empty map of headers and a
 // status code of -1
 func NewProxyResponseWriterV2() *ProxyResponseWriterV2 {
-	return &ProxyResponseWriterV2{
-		headers:   make(http.Header),
-		status:    defaultStatusCode,
-		observers: make([]chan<- bool, 0),
-	}
-
-}
-
-func (r *ProxyResponseWriterV2) CloseNotify() <-chan bool {
-	ch := make(chan bool, 1)
-
-	r.observers = append(r.observers, ch)
-
-	return ch
-}
-
-func (r *ProxyResponseWriterV2) notifyClosed() {
-	for _, v := range r.observers {
-		v <- true
-	}
-}
-
-// Header implementation from the http.ResponseWriter interface.
-func (r *ProxyResponseWriterV2) Header() http.Header {
-	return r.headers
-}
-
-// Write sets the response body in the object. If no status code
-// was set before with the WriteHeader method it sets the status
-// for the response to 200 OK.
-func (r *ProxyResponseWriterV2) Write(body []byte) (int, error) {
-	if r.status == defaultStatusCode {
-		r.status = http.StatusOK
-	}
-
-	// if the content type header is not set when we write the body we try to
-	// detect one and set it by default. If the content type cannot be detected
-	// it is automatically set to "application/octet-stream" by the
-	// DetectContentType method
-	if r.Header().Get(contentTypeHeaderKey) == "" {
-		r.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
-	}
-
-	return (&r.body).Write(body)
-}
-
-// WriteHeader sets a status code for the response. This method is used
-// for error responses.
-func (r *ProxyResponseWriterV2) WriteHeader(status int) {
-	r.status = status
+	return &ProxyResponseWriterV2{bufferedResponse: newBufferedResponse()}
 }
 
 // GetProxyResponse converts the data passed to the response writer into
@@ -84,22 +29,13 @@ func (r *ProxyResponseWriterV2) WriteHeader(status int) {
 // has no headers or an invalid status code returns an error.
 func (r *ProxyResponseWriterV2) GetProxyResponse() (events.APIGatewayV2HTTPResponse, error) {
 	r.notifyClosed()
+	promoteTrailers(r.headers)
 
 	if r.status == defaultStatusCode {
 		return events.APIGatewayV2HTTPResponse{}, errors.New("Status code not set on response")
 	}
 
-	var output string
-	isBase64 := false
-
-	bb := (&r.body).Bytes()
-
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
-		output = base64.StdEncoding.EncodeToString(bb)
-		isBase64 = true
-	}
+	output, isBase64 := r.encodeBody()
 
 	headers := make(map[string]string)
 	cookies := make([]string, 0)
@@ -112,11 +48,19 @@ func (r *ProxyResponseWriterV2) GetProxyResponse() (events.APIGatewayV2HTTPRespo
 		headers[headerKey] = strings.Join(headerValue, ",")
 	}
 
-	return events.APIGatewayV2HTTPResponse{
+	resp := events.APIGatewayV2HTTPResponse{
 		StatusCode:      r.status,
 		Headers:         headers,
 		Body:            output,
 		IsBase64Encoded: isBase64,
 		Cookies:         cookies,
-	}, nil
+	}
+
+	limit := r.payload.maxSize(MaxResponseSizeAPIGateway)
+	if exceeded, size := exceedsPayloadLimit(resp, len(output), r.headers, limit); exceeded {
+		appLog.Error("Response exceeds the API Gateway payload limit", "status", r.status, "size", size, "limit", limit)
+		return ResponseTooLargeV2(size, limit), nil
+	}
+
+	return resp, nil
 }
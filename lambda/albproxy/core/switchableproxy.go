@@ -0,0 +1,201 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SwitchableRequest is a container for an ALBTargetGroupRequest, an APIGatewayProxyRequest or an
+// APIGatewayV2HTTPRequest. It extends SwitchableAPIGatewayRequest with ALB events so a single
+// function can sit behind any of the three front ends.
+type SwitchableRequest struct {
+	v interface{} // v is Always nil, or a pointer of ALBTargetGroupRequest, APIGatewayProxyRequest or APIGatewayV2HTTPRequest
+}
+
+// NewSwitchableRequestALB creates a new SwitchableRequest from ALBTargetGroupRequest
+func NewSwitchableRequestALB(v *events.ALBTargetGroupRequest) *SwitchableRequest {
+	return &SwitchableRequest{
+		v: v,
+	}
+}
+
+// NewSwitchableRequestV1 creates a new SwitchableRequest from APIGatewayProxyRequest
+func NewSwitchableRequestV1(v *events.APIGatewayProxyRequest) *SwitchableRequest {
+	return &SwitchableRequest{
+		v: v,
+	}
+}
+
+// NewSwitchableRequestV2 creates a new SwitchableRequest from APIGatewayV2HTTPRequest
+func NewSwitchableRequestV2(v *events.APIGatewayV2HTTPRequest) *SwitchableRequest {
+	return &SwitchableRequest{
+		v: v,
+	}
+}
+
+// MarshalJSON is a pass through serialization
+func (s *SwitchableRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.v)
+}
+
+// UnmarshalJSON is a switching serialization based on the presence of fields in the
+// source JSON: requestContext.elb for ALBTargetGroupRequest, version 2.0 or rawQueryString for
+// APIGatewayV2HTTPRequest and httpMethod for APIGatewayProxyRequest.
+func (s *SwitchableRequest) UnmarshalJSON(b []byte) error {
+	s.v = nil
+	switch detectProxyEvent(b) {
+	case proxyEventALB:
+		s.v = &events.ALBTargetGroupRequest{}
+	case proxyEventV1:
+		s.v = &events.APIGatewayProxyRequest{}
+	case proxyEventV2:
+		s.v = &events.APIGatewayV2HTTPRequest{}
+	default:
+		return errors.New("unable to determine request type")
+	}
+	return json.Unmarshal(b, s.v)
+}
+
+// ALB returns the contained events.ALBTargetGroupRequest or nil
+func (s *SwitchableRequest) ALB() *events.ALBTargetGroupRequest {
+	switch v := s.v.(type) {
+	case *events.ALBTargetGroupRequest:
+		return v
+	case events.ALBTargetGroupRequest:
+		return &v
+	}
+	return nil
+}
+
+// Version1 returns the contained events.APIGatewayProxyRequest or nil
+func (s *SwitchableRequest) Version1() *events.APIGatewayProxyRequest {
+	switch v := s.v.(type) {
+	case *events.APIGatewayProxyRequest:
+		return v
+	case events.APIGatewayProxyRequest:
+		return &v
+	}
+	return nil
+}
+
+// Version2 returns the contained events.APIGatewayV2HTTPRequest or nil
+func (s *SwitchableRequest) Version2() *events.APIGatewayV2HTTPRequest {
+	switch v := s.v.(type) {
+	case *events.APIGatewayV2HTTPRequest:
+		return v
+	case events.APIGatewayV2HTTPRequest:
+		return &v
+	}
+	return nil
+}
+
+// SwitchableResponse is a container for an ALBTargetGroupResponse, an APIGatewayProxyResponse or an
+// APIGatewayV2HTTPResponse. Unlike SwitchableAPIGatewayResponse it is never guessed from the JSON;
+// it is built from the type of the SwitchableRequest it answers.
+type SwitchableResponse struct {
+	v interface{}
+}
+
+// NewSwitchableResponseALB creates a new SwitchableResponse from ALBTargetGroupResponse
+func NewSwitchableResponseALB(v *events.ALBTargetGroupResponse) *SwitchableResponse {
+	return &SwitchableResponse{
+		v: v,
+	}
+}
+
+// NewSwitchableResponseV1 creates a new SwitchableResponse from APIGatewayProxyResponse
+func NewSwitchableResponseV1(v *events.APIGatewayProxyResponse) *SwitchableResponse {
+	return &SwitchableResponse{
+		v: v,
+	}
+}
+
+// NewSwitchableResponseV2 creates a new SwitchableResponse from APIGatewayV2HTTPResponse
+func NewSwitchableResponseV2(v *events.APIGatewayV2HTTPResponse) *SwitchableResponse {
+	return &SwitchableResponse{
+		v: v,
+	}
+}
+
+// MarshalJSON is a pass through serialization
+func (s *SwitchableResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.v)
+}
+
+// ALB returns the contained events.ALBTargetGroupResponse or nil
+func (s *SwitchableResponse) ALB() *events.ALBTargetGroupResponse {
+	switch v := s.v.(type) {
+	case *events.ALBTargetGroupResponse:
+		return v
+	case events.ALBTargetGroupResponse:
+		return &v
+	}
+	return nil
+}
+
+// Version1 returns the contained events.APIGatewayProxyResponse or nil
+func (s *SwitchableResponse) Version1() *events.APIGatewayProxyResponse {
+	switch v := s.v.(type) {
+	case *events.APIGatewayProxyResponse:
+		return v
+	case events.APIGatewayProxyResponse:
+		return &v
+	}
+	return nil
+}
+
+// Version2 returns the contained events.APIGatewayV2HTTPResponse or nil
+func (s *SwitchableResponse) Version2() *events.APIGatewayV2HTTPResponse {
+	switch v := s.v.(type) {
+	case *events.APIGatewayV2HTTPResponse:
+		return v
+	case events.APIGatewayV2HTTPResponse:
+		return &v
+	}
+	return nil
+}
+
+type proxyEventType int
+
+const (
+	proxyEventUnknown proxyEventType = iota
+	proxyEventALB
+	proxyEventV1
+	proxyEventV2
+)
+
+// detectProxyEvent inspects the top level fields of a raw proxy event.
+// ALB and REST events share most of their shape, so the ELB request context is checked first.
+func detectProxyEvent(b []byte) proxyEventType {
+	delta := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &delta); err != nil {
+		return proxyEventUnknown
+	}
+
+	rc := map[string]json.RawMessage{}
+	if raw, ok := delta["requestContext"]; ok {
+		// a null or malformed request context simply leaves rc empty
+		_ = json.Unmarshal(raw, &rc)
+	}
+
+	var version string
+	if raw, ok := delta["version"]; ok {
+		_ = json.Unmarshal(raw, &version)
+	}
+
+	_, elbTest := rc["elb"]
+	_, v2test := delta["rawQueryString"]
+	_, v1test := delta["httpMethod"]
+
+	switch {
+	case elbTest:
+		return proxyEventALB
+	case version == "2.0" || v2test:
+		return proxyEventV2
+	case v1test:
+		return proxyEventV1
+	}
+	return proxyEventUnknown
+}
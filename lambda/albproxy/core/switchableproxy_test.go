@@ -0,0 +1,46 @@
+package core_test
+
+import (
+	"encoding/json"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SwitchableRequest", func() {
+	Context("UnmarshalJSON", func() {
+		It("detects ALB events by their ELB request context", func() {
+			var req core.SwitchableRequest
+			err := json.Unmarshal([]byte(`{"httpMethod":"GET","path":"/hello","multiValueQueryStringParameters":{},"requestContext":{"elb":{"targetGroupArn":"arn"}}}`), &req)
+			Expect(err).To(BeNil())
+			Expect(req.ALB()).ToNot(BeNil())
+			Expect(req.ALB().Path).To(Equal("/hello"))
+			Expect(req.Version1()).To(BeNil())
+			Expect(req.Version2()).To(BeNil())
+		})
+
+		It("detects API Gateway v1 events", func() {
+			var req core.SwitchableRequest
+			err := json.Unmarshal([]byte(`{"httpMethod":"GET","path":"/hello","multiValueQueryStringParameters":null,"requestContext":{"stage":"prod"}}`), &req)
+			Expect(err).To(BeNil())
+			Expect(req.Version1()).ToNot(BeNil())
+			Expect(req.ALB()).To(BeNil())
+		})
+
+		It("detects API Gateway v2 and Function URL events", func() {
+			var req core.SwitchableRequest
+			err := json.Unmarshal([]byte(`{"version":"2.0","rawPath":"/hello","rawQueryString":"","requestContext":{"http":{"method":"GET"}}}`), &req)
+			Expect(err).To(BeNil())
+			Expect(req.Version2()).ToNot(BeNil())
+			Expect(req.Version2().RawPath).To(Equal("/hello"))
+		})
+
+		It("rejects unknown payloads", func() {
+			var req core.SwitchableRequest
+			err := json.Unmarshal([]byte(`{"Records":[]}`), &req)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -9,7 +9,11 @@ import (
 
 // GatewayTimeout returns a dafault Gateway Timeout (504) response
 func GatewayTimeout() events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{StatusCode: http.StatusGatewayTimeout}
+	return events.APIGatewayProxyResponse{
+		StatusCode:        http.StatusGatewayTimeout,
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              jsonErrorBody(http.StatusGatewayTimeout),
+	}
 }
 
 // NewLoggedError generates a new error and logs it to stdout
@@ -18,3 +22,39 @@ func NewLoggedError(format string, a ...interface{}) error {
 	fmt.Println(err.Error())
 	return err
 }
+
+// jsonErrorBody returns an error body in the same shape as the JSON errors
+// written by the web package.
+func jsonErrorBody(status int) string {
+	return fmt.Sprintf(`{"Val":%q,"Err":null,"Status":%d}`, http.StatusText(status), status)
+}
+
+// responseTooLargeBody returns the error body of a handler response over the payload
+// limit of the front end.
+func responseTooLargeBody(size, limit int) string {
+	msg := fmt.Sprintf("Response of %d bytes exceeds the payload limit of %d bytes", size, limit)
+	return fmt.Sprintf(`{"Val":%q,"Err":null,"Status":%d}`, msg, http.StatusRequestEntityTooLarge)
+}
+
+func jsonErrorHeaders() http.Header {
+	return http.Header{contentTypeHeaderKey: {"application/json"}}
+}
+
+// InternalServerError returns a default Internal Server Error (500) response
+func InternalServerError() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode:        http.StatusInternalServerError,
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              jsonErrorBody(http.StatusInternalServerError),
+	}
+}
+
+// ResponseTooLarge returns the Request Entity Too Large (413) response sent instead
+// of a handler response of size bytes, over the payload limit of the front end.
+func ResponseTooLarge(size, limit int) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode:        http.StatusRequestEntityTooLarge,
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              responseTooLargeBody(size, limit),
+	}
+}
@@ -6,6 +6,33 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 )
 
+// GatewayTimeoutALB returns a default Gateway Timeout (504) response
 func GatewayTimeoutALB() events.ALBTargetGroupResponse {
-	return events.ALBTargetGroupResponse{StatusCode: http.StatusGatewayTimeout}
+	return events.ALBTargetGroupResponse{
+		StatusCode:        http.StatusGatewayTimeout,
+		StatusDescription: http.StatusText(http.StatusGatewayTimeout),
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              jsonErrorBody(http.StatusGatewayTimeout),
+	}
+}
+
+// InternalServerErrorALB returns a default Internal Server Error (500) response
+func InternalServerErrorALB() events.ALBTargetGroupResponse {
+	return events.ALBTargetGroupResponse{
+		StatusCode:        http.StatusInternalServerError,
+		StatusDescription: http.StatusText(http.StatusInternalServerError),
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              jsonErrorBody(http.StatusInternalServerError),
+	}
+}
+
+// ResponseTooLargeALB returns the Request Entity Too Large (413) response sent
+// instead of a handler response over the payload limit, see ResponseTooLarge.
+func ResponseTooLargeALB(size, limit int) events.ALBTargetGroupResponse {
+	return events.ALBTargetGroupResponse{
+		StatusCode:        http.StatusRequestEntityTooLarge,
+		StatusDescription: http.StatusText(http.StatusRequestEntityTooLarge),
+		MultiValueHeaders: jsonErrorHeaders(),
+		Body:              responseTooLargeBody(size, limit),
+	}
 }
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// aws-lambda-go has no VPC Lattice events, the types below follow the event
+// structures documented for Lambda targets of VPC Lattice target groups.
+
+// VPCLatticeRequest is the event of target groups with event structure version V1.
+type VPCLatticeRequest struct {
+	RawPath               string            `json:"raw_path"`
+	Method                string            `json:"method"`
+	Headers               map[string]string `json:"headers"`
+	QueryStringParameters map[string]string `json:"query_string_parameters"`
+	Body                  string            `json:"body"`
+	IsBase64Encoded       bool              `json:"is_base64_encoded"`
+}
+
+// VPCLatticeRequestV2 is the event of target groups with event structure version V2.
+type VPCLatticeRequestV2 struct {
+	Version               string                   `json:"version"`
+	Path                  string                   `json:"path"`
+	Method                string                   `json:"method"`
+	Headers               VPCLatticeValues         `json:"headers"`
+	QueryStringParameters VPCLatticeValues         `json:"queryStringParameters"`
+	Body                  string                   `json:"body"`
+	IsBase64Encoded       bool                     `json:"isBase64Encoded"`
+	RequestContext        VPCLatticeRequestContext `json:"requestContext"`
+}
+
+// VPCLatticeRequestContext describes the service network, service and target group a
+// V2 event was routed through, and the caller.
+type VPCLatticeRequestContext struct {
+	ServiceNetworkARN string             `json:"serviceNetworkArn"`
+	ServiceARN        string             `json:"serviceArn"`
+	TargetGroupARN    string             `json:"targetGroupArn"`
+	Identity          VPCLatticeIdentity `json:"identity"`
+	Region            string             `json:"region"`
+	// TimeEpoch is the time of the request in microseconds since the epoch.
+	TimeEpoch string `json:"timeEpoch"`
+}
+
+// VPCLatticeIdentity is the caller identity from the auth context of a V2 event. Type
+// is AWS_IAM for callers authenticated by the IAM auth policy of the service, and NONE
+// otherwise, in which case only the source VPC is known.
+type VPCLatticeIdentity struct {
+	SourceVpcARN   string `json:"sourceVpcArn"`
+	Type           string `json:"type"`
+	Principal      string `json:"principal"`
+	PrincipalOrgID string `json:"principalOrgID"`
+	SessionName    string `json:"sessionName"`
+	X509IssuerOu   string `json:"x509IssuerOu"`
+	X509SanDNS     string `json:"x509SanDns"`
+	X509SanNameCn  string `json:"x509SanNameCn"`
+	X509SanURI     string `json:"x509SanUri"`
+	X509SubjectCn  string `json:"x509SubjectCn"`
+}
+
+// VPCLatticeValues holds the headers and query parameters of V2 events. Each value may
+// be delivered as a string or as a list of strings.
+type VPCLatticeValues map[string][]string
+
+// UnmarshalJSON accepts both single and multi value objects.
+func (v *VPCLatticeValues) UnmarshalJSON(b []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	values := make(VPCLatticeValues, len(raw))
+	for k, r := range raw {
+		var list []string
+		if err := json.Unmarshal(r, &list); err == nil {
+			values[k] = list
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			return err
+		}
+		values[k] = []string{s}
+	}
+	*v = values
+	return nil
+}
+
+// VPCLatticeResponse is the response of Lambda targets for both event structure versions.
+type VPCLatticeResponse struct {
+	StatusCode        int               `json:"statusCode"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	IsBase64Encoded   bool              `json:"isBase64Encoded"`
+}
+
+// GatewayTimeoutLattice returns a default Gateway Timeout (504) response
+func GatewayTimeoutLattice() VPCLatticeResponse {
+	return VPCLatticeResponse{
+		StatusCode:        http.StatusGatewayTimeout,
+		StatusDescription: latticeStatusDescription(http.StatusGatewayTimeout),
+		Headers:           map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:              jsonErrorBody(http.StatusGatewayTimeout),
+	}
+}
+
+// ResponseTooLargeLattice returns the Request Entity Too Large (413) response sent
+// instead of a handler response over the payload limit, see ResponseTooLarge.
+func ResponseTooLargeLattice(size, limit int) VPCLatticeResponse {
+	return VPCLatticeResponse{
+		StatusCode:        http.StatusRequestEntityTooLarge,
+		StatusDescription: latticeStatusDescription(http.StatusRequestEntityTooLarge),
+		Headers:           map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:              responseTooLargeBody(size, limit),
+	}
+}
+
+// InternalServerErrorLattice returns a default Internal Server Error (500) response
+func InternalServerErrorLattice() VPCLatticeResponse {
+	return VPCLatticeResponse{
+		StatusCode:        http.StatusInternalServerError,
+		StatusDescription: latticeStatusDescription(http.StatusInternalServerError),
+		Headers:           map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:              jsonErrorBody(http.StatusInternalServerError),
+	}
+}
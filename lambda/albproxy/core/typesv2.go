@@ -6,6 +6,30 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 )
 
+// GatewayTimeoutV2 returns a default Gateway Timeout (504) response
 func GatewayTimeoutV2() events.APIGatewayV2HTTPResponse {
-	return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusGatewayTimeout}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusGatewayTimeout,
+		Headers:    map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:       jsonErrorBody(http.StatusGatewayTimeout),
+	}
+}
+
+// InternalServerErrorV2 returns a default Internal Server Error (500) response
+func InternalServerErrorV2() events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusInternalServerError,
+		Headers:    map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:       jsonErrorBody(http.StatusInternalServerError),
+	}
+}
+
+// ResponseTooLargeV2 returns the Request Entity Too Large (413) response sent
+// instead of a handler response over the payload limit, see ResponseTooLarge.
+func ResponseTooLargeV2(size, limit int) events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Headers:    map[string]string{contentTypeHeaderKey: "application/json"},
+		Body:       responseTooLargeBody(size, limit),
+	}
 }
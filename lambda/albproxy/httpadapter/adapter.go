@@ -0,0 +1,78 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type HandlerAdapter struct {
+	core.RequestAccessor
+	handler http.Handler
+	config
+}
+
+func New(handler http.Handler, opts ...Option) *HandlerAdapter {
+	h := &HandlerAdapter{
+		handler: handler,
+		config:  newConfig(opts),
+	}
+	h.warnUnusedHooks("API Gateway v1", hooksFor[events.APIGatewayProxyRequest, events.APIGatewayProxyResponse]())
+	return h
+}
+
+// Proxy receives an API Gateway REST (v1) proxy event, transforms it into an http.Request
+// object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapter) Proxy(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return intercept(&h.config, context.Background(), event, func(_ context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req, err := h.ProxyEventToHTTPRequest(event)
+		return h.proxyInternal(req, err)
+	})
+}
+
+// ProxyWithContext receives context and an API Gateway REST (v1) proxy event,
+// transforms them into an http.Request object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapter) ProxyWithContext(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyWithContext)
+}
+
+func (h *HandlerAdapter) proxyWithContext(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	appLog.Debug("Received API Gateway Request", "event", event)
+	req, err := h.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
+	} else {
+		appLog.Debug("Converted proxy event to request", "event", event, "header", req.Header, "method", req.Method, "URL", req.URL)
+	}
+	return h.proxyInternal(req, err)
+}
+
+func (h *HandlerAdapter) proxyInternal(req *http.Request, err error) (events.APIGatewayProxyResponse, error) {
+	if err != nil {
+		return core.GatewayTimeout(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+
+	w := core.NewProxyResponseWriter()
+	w.SetPayloadOptions(h.payloadOptions(req))
+	inv := startInvocation()
+	if !h.serve(h.handler, http.ResponseWriter(w), req) {
+		h.finishInvocation(inv, nil, "method", req.Method, "path", req.URL.Path, "timeout", true)
+		return core.GatewayTimeout(), nil
+	}
+	h.finishInvocation(inv, w.Header(), "method", req.Method, "path", req.URL.Path)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		appLog.Error("Error while generating proxy response", "err", err)
+		return core.GatewayTimeout(), core.NewLoggedError("Error while generating proxy response: %v", err)
+	} else {
+		appLog.Debug("Generated proxy response", "resp", resp)
+	}
+
+	return resp, nil
+}
@@ -20,49 +20,71 @@ func init() {
 type HandlerAdapterALB struct {
 	core.RequestAccessorALB
 	handler http.Handler
+	config
 }
 
-func NewALB(handler http.Handler) *HandlerAdapterALB {
-	return &HandlerAdapterALB{
+func NewALB(handler http.Handler, opts ...Option) *HandlerAdapterALB {
+	h := &HandlerAdapterALB{
 		handler: handler,
+		config:  newConfig(opts),
 	}
+	h.warnUnusedHooks("ALB", hooksFor[events.ALBTargetGroupRequest, events.ALBTargetGroupResponse]())
+	return h
 }
 
 // Proxy receives an ALB Target Group proxy event, transforms it into an http.Request
 // object, and sends it to the http.HandlerFunc for routing.
 // It returns a proxy response object generated from the http.ResponseWriter.
 func (h *HandlerAdapterALB) Proxy(event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
-	req, err := h.ProxyEventToHTTPRequest(event)
-	return h.proxyInternal(req, err)
+	return intercept(&h.config, context.Background(), event, func(_ context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		req, err := h.ProxyEventToHTTPRequest(event)
+		return h.proxyInternal(req, core.IsMultiValueALB(event), err)
+	})
 }
 
 // ProxyWithContext receives context and an ALB proxy event,
 // transforms them into an http.Request object, and sends it to the http.Handler for routing.
 // It returns a proxy response object generated from the http.ResponseWriter.
 func (h *HandlerAdapterALB) ProxyWithContext(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyWithContext)
+}
+
+func (h *HandlerAdapterALB) proxyWithContext(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
 	appLog.Debug("Received ABL Request", "event", event)
 	req, err := h.EventToRequestWithContext(ctx, event)
 	if err != nil {
 		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
 	} else {
-		appLog.Debug("Convered proxy event to request", "event", event, "header", req.Header, "method", req.Method, "URL", req.URL)
-
+		appLog.Debug("Converted proxy event to request", "event", event, "header", req.Header, "method", req.Method, "URL", req.URL)
 	}
-	return h.proxyInternal(req, err)
+	return h.proxyInternal(req, core.IsMultiValueALB(event), err)
 }
 
-func (h *HandlerAdapterALB) proxyInternal(req *http.Request, err error) (events.ALBTargetGroupResponse, error) {
+// proxyInternal serves req and answers in the header mode of the request event.
+func (h *HandlerAdapterALB) proxyInternal(req *http.Request, multiValue bool, err error) (events.ALBTargetGroupResponse, error) {
+	timeout := core.GatewayTimeoutALB()
+	if !multiValue {
+		timeout = core.SingleValueHeadersALB(timeout)
+	}
+
 	if err != nil {
-		return core.GatewayTimeoutALB(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+		return timeout, core.NewLoggedError("Could not convert proxy event to request: %v", err)
 	}
 
 	w := core.NewProxyResponseWriterALB()
-	h.handler.ServeHTTP(http.ResponseWriter(w), req)
+	w.SetPayloadOptions(h.payloadOptions(req))
+	w.SetMultiValueHeaders(multiValue)
+	inv := startInvocation()
+	if !h.serve(h.handler, http.ResponseWriter(w), req) {
+		h.finishInvocation(inv, nil, "method", req.Method, "path", req.URL.Path, "timeout", true)
+		return timeout, nil
+	}
+	h.finishInvocation(inv, w.Header(), "method", req.Method, "path", req.URL.Path)
 
 	resp, err := w.GetProxyResponse()
 	if err != nil {
 		appLog.Error("Error while generating proxy response", "err", err)
-		return core.GatewayTimeoutALB(), core.NewLoggedError("Error while generating proxy response: %v", err)
+		return timeout, core.NewLoggedError("Error while generating proxy response: %v", err)
 	} else {
 		appLog.Debug("Generated proxy response", "resp", resp)
 	}
@@ -0,0 +1,88 @@
+package httpadapter_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func albEvent(method, path string) events.ALBTargetGroupRequest {
+	return events.ALBTargetGroupRequest{
+		HTTPMethod: method,
+		Path:       path,
+		Headers:    map[string]string{"host": "example.com", "x-amzn-trace-id": "Root=1-abc"},
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: "arn"},
+		},
+	}
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+var _ = Describe("HandlerAdapterALB", func() {
+	Context("deadline", func() {
+		It("returns a JSON 504 when the handler overruns the deadline margin", func() {
+			handlerCtx := make(chan context.Context, 1)
+			adapter := httpadapter.NewALB(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerCtx <- r.Context()
+				<-r.Context().Done()
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+			}), httpadapter.WithDeadlineMargin(900*time.Millisecond))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			resp, err := adapter.ProxyWithContext(ctx, albEvent("GET", "/slow"))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusGatewayTimeout))
+			Expect(resp.MultiValueHeaders).To(BeNil())
+			Expect(resp.Headers["Content-Type"]).To(Equal("application/json"))
+			Expect(resp.Body).To(MatchJSON(`{"Val":"Gateway Timeout","Err":null,"Status":504}`))
+
+			deadline, ok := (<-handlerCtx).Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(time.Until(deadline)).To(BeNumerically("<", 200*time.Millisecond))
+		})
+
+		It("returns the handler response when it finishes in time", func() {
+			adapter := httpadapter.NewALB(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			resp, err := adapter.ProxyWithContext(ctx, albEvent("GET", "/fast"))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(Equal("ok"))
+		})
+
+		It("re-raises a handler panic with the stack of the handler", func() {
+			adapter := httpadapter.NewALB(http.HandlerFunc(panickingHandler))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var p interface{}
+			func() {
+				defer func() { p = recover() }()
+				adapter.ProxyWithContext(ctx, albEvent("GET", "/panic"))
+			}()
+			err, ok := p.(error)
+			Expect(ok).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix("boom"))
+			Expect(err.Error()).To(ContainSubstring("httpadapter_test.panickingHandler"))
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+)
+
+// HandlerAdapterLattice serves VPC Lattice target groups of type Lambda. Use
+// ProxyWithContext for event structure version V1 and ProxyV2WithContext for V2, or
+// Router.HandleLattice for both. The caller identity of V2 events is available to the
+// handler through core.GetLatticeIdentityFromContext.
+type HandlerAdapterLattice struct {
+	core.RequestAccessorLattice
+	handler http.Handler
+	config
+}
+
+func NewLattice(handler http.Handler, opts ...Option) *HandlerAdapterLattice {
+	h := &HandlerAdapterLattice{
+		handler: handler,
+		config:  newConfig(opts),
+	}
+	h.warnUnusedHooks("VPC Lattice",
+		hooksFor[core.VPCLatticeRequest, core.VPCLatticeResponse](),
+		hooksFor[core.VPCLatticeRequestV2, core.VPCLatticeResponse]())
+	return h
+}
+
+// ProxyWithContext receives context and a V1 VPC Lattice event, transforms them into an
+// http.Request object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapterLattice) ProxyWithContext(ctx context.Context, event core.VPCLatticeRequest) (core.VPCLatticeResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyWithContext)
+}
+
+func (h *HandlerAdapterLattice) proxyWithContext(ctx context.Context, event core.VPCLatticeRequest) (core.VPCLatticeResponse, error) {
+	appLog.Debug("Received VPC Lattice Request", "event", event)
+	req, err := h.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
+	}
+	return h.proxyInternal(req, err)
+}
+
+// ProxyV2WithContext receives context and a V2 VPC Lattice event, transforms them into an
+// http.Request object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapterLattice) ProxyV2WithContext(ctx context.Context, event core.VPCLatticeRequestV2) (core.VPCLatticeResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyV2WithContext)
+}
+
+func (h *HandlerAdapterLattice) proxyV2WithContext(ctx context.Context, event core.VPCLatticeRequestV2) (core.VPCLatticeResponse, error) {
+	appLog.Debug("Received VPC Lattice V2 Request", "event", event)
+	req, err := h.EventToRequestV2WithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
+	}
+	return h.proxyInternal(req, err)
+}
+
+func (h *HandlerAdapterLattice) proxyInternal(req *http.Request, err error) (core.VPCLatticeResponse, error) {
+	if err != nil {
+		return core.GatewayTimeoutLattice(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+
+	w := core.NewProxyResponseWriterLattice()
+	w.SetPayloadOptions(h.payloadOptions(req))
+	inv := startInvocation()
+	if !h.serve(h.handler, http.ResponseWriter(w), req) {
+		h.finishInvocation(inv, nil, "method", req.Method, "path", req.URL.Path, "timeout", true)
+		return core.GatewayTimeoutLattice(), nil
+	}
+	h.finishInvocation(inv, w.Header(), "method", req.Method, "path", req.URL.Path)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		appLog.Error("Error while generating proxy response", "err", err)
+		return core.GatewayTimeoutLattice(), core.NewLoggedError("Error while generating proxy response: %v", err)
+	}
+	appLog.Debug("Generated proxy response", "resp", resp)
+
+	return resp, nil
+}
@@ -0,0 +1,76 @@
+package httpadapter_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HandlerAdapterLattice", func() {
+	var adapter *httpadapter.HandlerAdapterLattice
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+			id, ok := core.GetLatticeIdentityFromContext(r.Context())
+			if !ok {
+				http.Error(w, "no identity", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Add("Set-Cookie", "a=1")
+			w.Write([]byte(id.Principal))
+		})
+		mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})
+		adapter = httpadapter.NewLattice(mux)
+	})
+
+	It("serves V2 events with the caller identity", func() {
+		resp, err := adapter.ProxyV2WithContext(context.Background(), core.VPCLatticeRequestV2{
+			Version: "2.0",
+			Path:    "/whoami",
+			Method:  "GET",
+			Headers: core.VPCLatticeValues{"host": {"svc"}},
+			RequestContext: core.VPCLatticeRequestContext{
+				TargetGroupARN: "arn",
+				Identity:       core.VPCLatticeIdentity{Type: "AWS_IAM", Principal: "arn:aws:iam::000000000000:role/billing"},
+			},
+		})
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.StatusDescription).To(Equal("200 OK"))
+		Expect(resp.Body).To(Equal("arn:aws:iam::000000000000:role/billing"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Set-Cookie", "a=1"))
+	})
+
+	It("serves V1 events without identity", func() {
+		resp, err := adapter.ProxyWithContext(context.Background(), core.VPCLatticeRequest{RawPath: "/whoami", Method: "GET"})
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("returns a timeout response before the deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+		defer cancel()
+		ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{})
+
+		resp, err := adapter.ProxyWithContext(ctx, core.VPCLatticeRequest{RawPath: "/slow", Method: "GET"})
+		Expect(err).To(BeNil())
+		Expect(resp).To(Equal(core.GatewayTimeoutLattice()))
+	})
+
+	It("fails on events that cannot be converted", func() {
+		resp, err := adapter.ProxyV2WithContext(context.Background(), core.VPCLatticeRequestV2{Path: "/", Method: "POST", Body: "%%%", IsBase64Encoded: true})
+		Expect(err).ToNot(BeNil())
+		Expect(resp).To(Equal(core.GatewayTimeoutLattice()))
+	})
+})
@@ -0,0 +1,107 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerAdapterSQS dispatches SQS messages to an http.Handler. Each message becomes a
+// synthetic POST request, see core.RequestAccessorSQS, and any non-2xx response is
+// reported as a batch item failure so only the failed messages are retried.
+//
+// The event source mapping must have ReportBatchItemFailures enabled, otherwise Lambda
+// ignores the response and deletes the whole batch.
+type HandlerAdapterSQS struct {
+	core.RequestAccessorSQS
+	handler http.Handler
+	config
+}
+
+func NewSQS(handler http.Handler, opts ...Option) *HandlerAdapterSQS {
+	h := &HandlerAdapterSQS{
+		handler: handler,
+		config:  newConfig(opts),
+	}
+	h.warnUnusedHooks("SQS")
+	return h
+}
+
+// ProxyWithContext receives context and an SQS event and sends every message to the
+// http.Handler in order. Messages of FIFO queues after a failed one are reported as
+// failed too, so their order is kept when they are retried. Once less than the deadline
+// margin is left, the remaining messages are reported as failed without being served,
+// as a handler started then would keep running after its message is retried.
+func (h *HandlerAdapterSQS) ProxyWithContext(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	appLog.Debug("Received SQS Event", "records", len(event.Records))
+	inv := startInvocation()
+
+	resp := events.SQSEventResponse{BatchItemFailures: make([]events.SQSBatchItemFailure, 0)}
+	failed := false
+	for i, msg := range event.Records {
+		if h.outOfTime(ctx) {
+			appLog.Error("Lambda deadline reached, failing the remaining SQS messages", "remaining", len(event.Records)-i)
+			for _, rest := range event.Records[i:] {
+				resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: rest.MessageId})
+			}
+			break
+		}
+		if failed && core.IsFifoSQS(msg.EventSourceARN) {
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: msg.MessageId})
+			continue
+		}
+		if !h.proxyMessage(ctx, msg) {
+			failed = true
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: msg.MessageId})
+		}
+	}
+
+	if len(resp.BatchItemFailures) > 0 {
+		appLog.Info("SQS batch processed with failures", "records", len(event.Records), "failures", len(resp.BatchItemFailures))
+	}
+	h.finishInvocation(inv, nil, "records", len(event.Records), "failures", len(resp.BatchItemFailures))
+	return resp, nil
+}
+
+// outOfTime reports whether the invocation has no more than the deadline margin left.
+func (h *HandlerAdapterSQS) outOfTime(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) <= h.deadlineMargin
+}
+
+// proxyMessage serves a single message and reports whether it succeeded.
+func (h *HandlerAdapterSQS) proxyMessage(ctx context.Context, msg events.SQSMessage) (ok bool) {
+	req, err := h.EventToRequestWithContext(ctx, msg)
+	if err != nil {
+		appLog.Error("Could not convert SQS message to request", "messageId", msg.MessageId, "err", err)
+		return false
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			appLog.Error("Panic while processing SQS message", "messageId", msg.MessageId, "url", req.URL, "err", fmt.Sprint(p))
+			ok = false
+		}
+	}()
+
+	w := newStatusRecorder()
+	if !h.serve(h.handler, http.ResponseWriter(w), req) {
+		return false
+	}
+
+	status := w.Status()
+	if status < 200 || status > 299 {
+		appLog.Error("SQS message handler failed", "messageId", msg.MessageId, "url", req.URL, "status", status, "body", w.body.String())
+		return false
+	}
+	appLog.Debug("SQS message processed", "messageId", msg.MessageId, "url", req.URL, "status", status)
+	return true
+}
@@ -0,0 +1,127 @@
+package httpadapter_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func sqsMessage(id, arn, body string, attrs map[string]string) events.SQSMessage {
+	msg := events.SQSMessage{
+		MessageId:         id,
+		Body:              body,
+		EventSourceARN:    arn,
+		AWSRegion:         "ap-south-1",
+		MessageAttributes: map[string]events.SQSMessageAttribute{},
+	}
+	for k, v := range attrs {
+		v := v
+		msg.MessageAttributes[k] = events.SQSMessageAttribute{StringValue: &v, DataType: "String"}
+	}
+	return msg
+}
+
+var _ = Describe("HandlerAdapterSQS", func() {
+	var (
+		adapter *httpadapter.HandlerAdapterSQS
+		seen    []string
+	)
+
+	BeforeEach(func() {
+		seen = nil
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /attendance", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			msg, ok := core.GetSQSMessageFromContext(r.Context())
+			Expect(ok).To(BeTrue())
+			Expect(r.Header.Get(core.SQSMessageIDHeader)).To(Equal(msg.MessageId))
+			seen = append(seen, msg.MessageId)
+			if string(body) == `{"fail":true}` {
+				http.Error(w, "rejected", http.StatusUnprocessableEntity)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		})
+		mux.HandleFunc("POST /jobs/rollup", func(w http.ResponseWriter, r *http.Request) {
+			seen = append(seen, "rollup:"+r.Header.Get(core.SQSAttributeHeaderPrefix+"Tenant"))
+		})
+		mux.HandleFunc("POST /panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})
+		adapter = httpadapter.NewSQS(mux)
+	})
+
+	It("routes messages by queue name and reports only failed messages", func() {
+		arn := "arn:aws:sqs:ap-south-1:000000000000:attendance"
+		resp, err := adapter.ProxyWithContext(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+			sqsMessage("1", arn, `{"id":1}`, nil),
+			sqsMessage("2", arn, `{"fail":true}`, nil),
+			sqsMessage("3", arn, `{"id":3}`, nil),
+		}})
+		Expect(err).To(BeNil())
+		Expect(seen).To(Equal([]string{"1", "2", "3"}))
+		Expect(resp.BatchItemFailures).To(Equal([]events.SQSBatchItemFailure{{ItemIdentifier: "2"}}))
+	})
+
+	It("routes messages by attribute", func() {
+		adapter.RouteByAttribute("route")
+		arn := "arn:aws:sqs:ap-south-1:000000000000:jobs"
+		resp, err := adapter.ProxyWithContext(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+			sqsMessage("1", arn, `{}`, map[string]string{"route": "/jobs/rollup", "Tenant": "t1"}),
+			sqsMessage("2", arn, `{}`, map[string]string{"route": "/panic"}),
+			sqsMessage("3", arn, `{}`, map[string]string{"route": "/unknown"}),
+		}})
+		Expect(err).To(BeNil())
+		Expect(seen).To(Equal([]string{"rollup:t1"}))
+		Expect(resp.BatchItemFailures).To(Equal([]events.SQSBatchItemFailure{{ItemIdentifier: "2"}, {ItemIdentifier: "3"}}))
+	})
+
+	It("fails the rest of a FIFO batch after a failure", func() {
+		arn := "arn:aws:sqs:ap-south-1:000000000000:attendance.fifo"
+		adapter.RouteByAttribute("route")
+		resp, err := adapter.ProxyWithContext(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+			sqsMessage("1", arn, `{"fail":true}`, map[string]string{"route": "/attendance"}),
+			sqsMessage("2", arn, `{"id":2}`, map[string]string{"route": "/attendance"}),
+		}})
+		Expect(err).To(BeNil())
+		Expect(seen).To(Equal([]string{"1"}))
+		Expect(resp.BatchItemFailures).To(Equal([]events.SQSBatchItemFailure{{ItemIdentifier: "1"}, {ItemIdentifier: "2"}}))
+	})
+
+	It("fails the messages left after the deadline margin without serving them", func() {
+		arn := "arn:aws:sqs:ap-south-1:000000000000:jobs"
+		served := make(chan string, 3)
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+			msg, _ := core.GetSQSMessageFromContext(r.Context())
+			served <- msg.MessageId
+			if msg.MessageId == "1" {
+				// use up the budget up to the deadline margin
+				deadline, _ := r.Context().Deadline()
+				time.Sleep(time.Until(deadline) + 10*time.Millisecond)
+			}
+		})
+		adapter = httpadapter.NewSQS(mux, httpadapter.WithDeadlineMargin(100*time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		resp, err := adapter.ProxyWithContext(ctx, events.SQSEvent{Records: []events.SQSMessage{
+			sqsMessage("1", arn, `{}`, nil),
+			sqsMessage("2", arn, `{}`, nil),
+			sqsMessage("3", arn, `{}`, nil),
+		}})
+		Expect(err).To(BeNil())
+		Expect(served).To(Receive(Equal("1")))
+		Consistently(served, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(resp.BatchItemFailures).To(Equal([]events.SQSBatchItemFailure{{ItemIdentifier: "1"}, {ItemIdentifier: "2"}, {ItemIdentifier: "3"}}))
+	})
+})
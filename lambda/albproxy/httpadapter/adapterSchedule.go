@@ -0,0 +1,150 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/database"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// JobRequest is passed to scheduled jobs.
+type JobRequest struct {
+	// Name is the name the job was registered under.
+	Name string
+	// Event is the EventBridge event that triggered the run.
+	Event events.CloudWatchEvent
+	// Logger carries the job name and event ID.
+	Logger *slog.Logger
+	// DB is the database service of the adapter, for jobs that manage
+	// several transactions themselves.
+	DB database.Service
+}
+
+// Job is a scheduled job. Like the handlers wrapped by web.Exec it receives a
+// query wrapper that runs inside a transaction when the job is registered with
+// withTx; the transaction is committed only if the job returns nil.
+type Job func(ctx context.Context, req *JobRequest, q *database.Queries) error
+
+type scheduledJob struct {
+	fn     Job
+	withTx bool
+}
+
+// HandlerAdapterSchedule dispatches EventBridge rule and EventBridge Scheduler events
+// to registered jobs. A job is selected by the name of the rule or schedule in the
+// event resources, or else by the event detail-type.
+//
+// EventBridge rules send events with the rule ARN in resources. EventBridge Scheduler
+// sends the input of the schedule instead, which has neither resources nor a
+// detail-type unless the input sets them, for example
+//
+//	{"resources": ["<aws.scheduler.schedule-arn>"]}
+//
+// to select the job by schedule name, or {"detail-type": "attendance-rollup"}.
+// Schedules with any other input match no job, so their invocations fail.
+type HandlerAdapterSchedule struct {
+	db   database.Service
+	mu   sync.RWMutex
+	jobs map[string]scheduledJob
+	config
+}
+
+func NewSchedule(db database.Service, opts ...Option) *HandlerAdapterSchedule {
+	h := &HandlerAdapterSchedule{
+		db:     db,
+		jobs:   make(map[string]scheduledJob),
+		config: newConfig(opts),
+	}
+	h.warnUnusedHooks("schedule")
+	return h
+}
+
+// Register adds a job for the given rule name, schedule name or detail-type.
+// It is safe to call while events are dispatched, as in server mode.
+func (h *HandlerAdapterSchedule) Register(name string, job Job, withTx bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jobs[name] = scheduledJob{fn: job, withTx: withTx}
+}
+
+// ProxyWithContext receives context and an EventBridge event and runs the matching job.
+// The outcome and duration of every run are logged. A failed run returns its error so
+// the invocation is retried by EventBridge.
+func (h *HandlerAdapterSchedule) ProxyWithContext(ctx context.Context, event events.CloudWatchEvent) error {
+	name, job, ok := h.lookup(event)
+	if !ok {
+		appLog.Error("No job registered for event", "id", event.ID, "detailType", event.DetailType, "resources", event.Resources)
+		return core.NewLoggedError("No job registered for event %s (%s)", event.ID, event.DetailType)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-h.deadlineMargin))
+		defer cancel()
+	}
+
+	req := &JobRequest{
+		Name:   name,
+		Event:  event,
+		Logger: appLog.With("job", name, "eventId", event.ID),
+		DB:     h.db,
+	}
+
+	inv := startInvocation()
+	outcome := "panic"
+	var err error
+	defer func() {
+		req.Logger.Info("Scheduled job finished", "outcome", outcome, "duration", time.Since(inv.start), "err", err)
+		h.finishInvocation(inv, nil, "job", name, "outcome", outcome)
+	}()
+
+	req.Logger.Info("Scheduled job started", "detailType", event.DetailType, "time", event.Time)
+	err = h.run(ctx, req, job)
+	if err != nil {
+		outcome = "failure"
+		return fmt.Errorf("job %s failed: %w", name, err)
+	}
+	outcome = "success"
+	return nil
+}
+
+// lookup finds the job for an event. Rule ARNs look like rule/<name> or
+// rule/<bus>/<name>, schedule ARNs like schedule/<group>/<name>.
+func (h *HandlerAdapterSchedule) lookup(event events.CloudWatchEvent) (string, scheduledJob, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, arn := range event.Resources {
+		resource := arn[strings.LastIndex(arn, ":")+1:]
+		name := resource[strings.LastIndex(resource, "/")+1:]
+		if job, ok := h.jobs[name]; ok {
+			return name, job, true
+		}
+	}
+	job, ok := h.jobs[event.DetailType]
+	return event.DetailType, job, ok
+}
+
+// run executes the job with a query wrapper, in a transaction if required.
+func (h *HandlerAdapterSchedule) run(ctx context.Context, req *JobRequest, job scheduledJob) error {
+	if !job.withTx {
+		return job.fn(ctx, req, h.db.Queries())
+	}
+
+	tx, qtx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := job.fn(ctx, req, qtx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
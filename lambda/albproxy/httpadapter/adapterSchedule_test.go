@@ -0,0 +1,113 @@
+package httpadapter_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rsingh25/tukashi-lib/database"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeDB is a database.Service without a connection, for jobs that do not query.
+type fakeDB struct{}
+
+func (fakeDB) Health() map[string]string  { return map[string]string{"status": "up"} }
+func (fakeDB) Close() error               { return nil }
+func (fakeDB) Queries() *database.Queries { return database.New(nil) }
+func (fakeDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, *database.Queries, error) {
+	return nil, nil, errors.New("no transactions in fakeDB")
+}
+
+var _ = Describe("HandlerAdapterSchedule", func() {
+	var (
+		adapter *httpadapter.HandlerAdapterSchedule
+		ran     []string
+	)
+
+	BeforeEach(func() {
+		ran = nil
+		adapter = httpadapter.NewSchedule(fakeDB{})
+		adapter.Register("attendance-rollup", func(ctx context.Context, req *httpadapter.JobRequest, q *database.Queries) error {
+			Expect(q).ToNot(BeNil())
+			Expect(req.Logger).ToNot(BeNil())
+			ran = append(ran, req.Name)
+			return nil
+		}, false)
+		adapter.Register("Cleanup", func(ctx context.Context, req *httpadapter.JobRequest, q *database.Queries) error {
+			ran = append(ran, req.Name)
+			return errors.New("cleanup failed")
+		}, false)
+		adapter.Register("needs-tx", func(ctx context.Context, req *httpadapter.JobRequest, q *database.Queries) error {
+			ran = append(ran, req.Name)
+			return nil
+		}, true)
+	})
+
+	It("dispatches EventBridge rules by rule name", func() {
+		err := adapter.ProxyWithContext(context.Background(), events.CloudWatchEvent{
+			ID:         "1",
+			DetailType: "Scheduled Event",
+			Resources:  []string{"arn:aws:events:ap-south-1:000000000000:rule/attendance-rollup"},
+		})
+		Expect(err).To(BeNil())
+		Expect(ran).To(Equal([]string{"attendance-rollup"}))
+	})
+
+	It("dispatches EventBridge Scheduler schedules whose input sets the schedule ARN", func() {
+		// the input {"resources": ["<aws.scheduler.schedule-arn>"]} after substitution
+		var event events.CloudWatchEvent
+		err := json.Unmarshal([]byte(`{"resources": ["arn:aws:scheduler:ap-south-1:000000000000:schedule/default/attendance-rollup"]}`), &event)
+		Expect(err).To(BeNil())
+		Expect(adapter.ProxyWithContext(context.Background(), event)).To(Succeed())
+		Expect(ran).To(Equal([]string{"attendance-rollup"}))
+	})
+
+	It("rejects EventBridge Scheduler schedules with other input", func() {
+		var event events.CloudWatchEvent
+		err := json.Unmarshal([]byte(`{"tenant": "t1"}`), &event)
+		Expect(err).To(BeNil())
+		Expect(adapter.ProxyWithContext(context.Background(), event)).ToNot(Succeed())
+		Expect(ran).To(BeEmpty())
+	})
+
+	It("registers jobs while events are dispatched", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				adapter.Register(fmt.Sprintf("job-%d", i), func(ctx context.Context, req *httpadapter.JobRequest, q *database.Queries) error {
+					return nil
+				}, false)
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			adapter.ProxyWithContext(context.Background(), events.CloudWatchEvent{ID: "6", DetailType: "job-0"})
+		}
+		<-done
+	})
+
+	It("falls back to the detail-type and returns job failures", func() {
+		err := adapter.ProxyWithContext(context.Background(), events.CloudWatchEvent{ID: "3", DetailType: "Cleanup"})
+		Expect(err).To(MatchError(ContainSubstring("cleanup failed")))
+		Expect(ran).To(Equal([]string{"Cleanup"}))
+	})
+
+	It("does not run a transactional job when the transaction cannot start", func() {
+		err := adapter.ProxyWithContext(context.Background(), events.CloudWatchEvent{ID: "4", DetailType: "needs-tx"})
+		Expect(err).ToNot(BeNil())
+		Expect(ran).To(BeEmpty())
+	})
+
+	It("rejects events without a registered job", func() {
+		err := adapter.ProxyWithContext(context.Background(), events.CloudWatchEvent{ID: "5", DetailType: "Unknown"})
+		Expect(err).ToNot(BeNil())
+	})
+})
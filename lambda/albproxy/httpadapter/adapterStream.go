@@ -0,0 +1,91 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerAdapterStream adapts Lambda Function URL events to an http.Handler when the
+// Function URL uses the RESPONSE_STREAM invoke mode. The response is streamed to the
+// client while the handler runs, so Flush can be used for Server-Sent Events and large
+// downloads.
+//
+// Streaming responses require the provided.al2 / provided.al2023 runtimes or building
+// with the lambda.norpc tag.
+//
+// Of the options only WithTelemetry, WithContentSniffing and the hooks for Function
+// URL events with events.LambdaFunctionURLStreamingResponse responses apply; the
+// handler controls the response. An AfterResponseHook runs once the status code and headers are
+// committed, and may change them but not the streamed body.
+type HandlerAdapterStream struct {
+	core.RequestAccessorV2
+	handler http.Handler
+	config
+}
+
+func NewStream(handler http.Handler, opts ...Option) *HandlerAdapterStream {
+	h := &HandlerAdapterStream{
+		handler: handler,
+		config:  newConfig(opts),
+	}
+	h.warnUnusedHooks("stream", hooksFor[events.APIGatewayV2HTTPRequest, events.LambdaFunctionURLStreamingResponse]())
+	if h.payload.MaxSize > 0 || len(h.payload.BinaryMediaTypes) > 0 {
+		appLog.Warn("Streaming responses are not base64 encoded or size limited, ignoring WithMaxResponseSize and WithBinaryMediaTypes",
+			"maxSize", h.payload.MaxSize, "binaryMediaTypes", h.payload.BinaryMediaTypes)
+	}
+	return h
+}
+
+// ProxyWithContext receives context and a Function URL event, transforms them into an
+// http.Request object, and sends it to the http.Handler for routing.
+// It returns as soon as the handler commits its status code and headers; the body is
+// streamed to the runtime until the handler returns.
+func (h *HandlerAdapterStream) ProxyWithContext(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	resp, err := intercept(&h.config, ctx, event, h.proxyWithContext)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (h *HandlerAdapterStream) proxyWithContext(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.LambdaFunctionURLStreamingResponse, error) {
+	appLog.Debug("Received Function URL streaming Request", "event", event)
+	req, err := h.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
+		return events.LambdaFunctionURLStreamingResponse{}, core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+	appLog.Debug("Converted proxy event to request", "event", event, "header", req.Header, "method", req.Method, "URL", req.URL)
+
+	w := core.NewProxyResponseWriterStream()
+	w.SetPayloadOptions(h.payloadOptions(req))
+	go h.serve(w, req, startInvocation())
+
+	resp := w.GetStreamingResponse()
+	appLog.Debug("Streaming proxy response", "status", resp.StatusCode, "headers", resp.Headers)
+	return *resp, nil
+}
+
+// serve runs the handler and ends the stream once it returns. A panic aborts the
+// stream so the runtime reports the invocation as failed.
+func (h *HandlerAdapterStream) serve(w *core.ProxyResponseWriterStream, req *http.Request, inv *invocation) {
+	defer func() {
+		h.finishInvocation(inv, nil, "method", req.Method, "path", req.URL.Path)
+	}()
+	defer func() {
+		if p := recover(); p != nil {
+			appLog.Error("Panic while streaming response", "method", req.Method, "url", req.URL, "err", p)
+			w.CloseWithError(fmt.Errorf("panic while streaming response: %v", p))
+			return
+		}
+		if err := w.Close(); err != nil {
+			appLog.Error("Error while closing response stream", "err", err)
+		}
+	}()
+	h.handler.ServeHTTP(http.ResponseWriter(w), withAuthorizerUser(req))
+}
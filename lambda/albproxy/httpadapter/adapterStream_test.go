@@ -0,0 +1,247 @@
+package httpadapter_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRuntime is a minimal local implementation of the Lambda runtime API. Each
+// invocation's response body is exposed as a pipe so tests can observe chunks as
+// the runtime client streams them.
+type fakeRuntime struct {
+	server      *httptest.Server
+	invocations chan *fakeInvocation
+	done        chan struct{}
+	mu          sync.Mutex
+	pending     map[string]*fakeInvocation
+	seq         int
+}
+
+type fakeInvocation struct {
+	id      string
+	payload []byte
+	body    *io.PipeReader
+	bodyW   *io.PipeWriter
+	errored chan string
+}
+
+func newFakeRuntime() *fakeRuntime {
+	rt := &fakeRuntime{
+		invocations: make(chan *fakeInvocation),
+		done:        make(chan struct{}),
+		pending:     make(map[string]*fakeInvocation),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /2018-06-01/runtime/invocation/next", rt.next)
+	mux.HandleFunc("POST /2018-06-01/runtime/invocation/{id}/response", rt.response)
+	mux.HandleFunc("POST /2018-06-01/runtime/invocation/{id}/error", rt.error)
+	rt.server = httptest.NewServer(mux)
+	return rt
+}
+
+// invoke queues a payload for the function and returns the invocation handle.
+func (rt *fakeRuntime) invoke(payload []byte) *fakeInvocation {
+	rt.mu.Lock()
+	rt.seq++
+	pr, pw := io.Pipe()
+	inv := &fakeInvocation{id: strconv.Itoa(rt.seq), payload: payload, body: pr, bodyW: pw, errored: make(chan string, 1)}
+	rt.pending[inv.id] = inv
+	rt.mu.Unlock()
+
+	rt.invocations <- inv
+	return inv
+}
+
+// close stops the runtime; pending and later polls for the next invocation fail.
+func (rt *fakeRuntime) close() {
+	close(rt.done)
+	rt.server.Close()
+}
+
+func (rt *fakeRuntime) next(w http.ResponseWriter, r *http.Request) {
+	var inv *fakeInvocation
+	select {
+	case inv = <-rt.invocations:
+	case <-rt.done:
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	w.Header().Set("Lambda-Runtime-Aws-Request-Id", inv.id)
+	w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10))
+	w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", "arn:aws:lambda:ap-south-1:000000000000:function:test")
+	w.Write(inv.payload)
+}
+
+func (rt *fakeRuntime) lookup(r *http.Request) *fakeInvocation {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.pending[r.PathValue("id")]
+}
+
+func (rt *fakeRuntime) response(w http.ResponseWriter, r *http.Request) {
+	inv := rt.lookup(r)
+	_, err := io.Copy(inv.bodyW, r.Body)
+	if err == nil && r.Trailer.Get("Lambda-Runtime-Function-Error-Type") != "" {
+		err = fmt.Errorf("%s", r.Trailer.Get("Lambda-Runtime-Function-Error-Type"))
+	}
+	inv.bodyW.CloseWithError(err)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (rt *fakeRuntime) error(w http.ResponseWriter, r *http.Request) {
+	inv := rt.lookup(r)
+	b, _ := io.ReadAll(r.Body)
+	inv.errored <- string(b)
+	inv.bodyW.Close()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// readPrelude reads the JSON prelude and the 8 byte separator of a streamed
+// Function URL response.
+func readPrelude(r *bufio.Reader) map[string]interface{} {
+	raw, err := r.ReadBytes(0)
+	Expect(err).To(BeNil())
+	sep := make([]byte, 7)
+	_, err = io.ReadFull(r, sep)
+	Expect(err).To(BeNil())
+	Expect(sep).To(Equal([]byte{0, 0, 0, 0, 0, 0, 0}))
+
+	prelude := map[string]interface{}{}
+	Expect(json.Unmarshal(raw[:len(raw)-1], &prelude)).To(Succeed())
+	return prelude
+}
+
+// streamProcessVariable holds the URL the /events handler of the stream process waits
+// on before it writes its second event, see TestStreamProcess.
+const streamProcessVariable = "HTTPADAPTER_STREAM_PROCESS"
+
+// TestStreamProcess is not a test on its own: the HandlerAdapterStream specs run it in a
+// child process, serving the stream adapter through lambda.Start and the runtime client
+// of aws-lambda-go, which never return.
+func TestStreamProcess(t *testing.T) {
+	releaseURL, ok := os.LookupEnv(streamProcessVariable)
+	if !ok {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		fmt.Fprint(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		if resp, err := http.Get(releaseURL); err == nil {
+			resp.Body.Close()
+		}
+		fmt.Fprint(w, "data: two\n\n")
+	})
+	mux.HandleFunc("GET /missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	lambda.Start(httpadapter.NewStream(mux).ProxyWithContext)
+}
+
+var _ = Describe("HandlerAdapterStream", func() {
+	var (
+		rt            *fakeRuntime
+		releaseServer *httptest.Server
+		release       chan struct{}
+		cmd           *exec.Cmd
+	)
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+		releaseServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+
+		rt = newFakeRuntime()
+		cmd, _ = startTestProcess("TestStreamProcess",
+			streamProcessVariable+"="+releaseServer.URL,
+			httpadapter.RuntimeAPIVariable+"="+strings.TrimPrefix(rt.server.URL, "http://"))
+	})
+
+	AfterEach(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		cmd.Process.Kill()
+		cmd.Wait()
+		rt.close()
+		releaseServer.Close()
+	})
+
+	invoke := func(path string) *fakeInvocation {
+		payload, err := json.Marshal(events.APIGatewayV2HTTPRequest{
+			Version:        "2.0",
+			RawPath:        path,
+			RequestContext: events.APIGatewayV2HTTPRequestContext{DomainName: "abc.lambda-url.ap-south-1.on.aws", HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", Path: path}},
+		})
+		Expect(err).To(BeNil())
+		return rt.invoke(payload)
+	}
+
+	It("pushes flushed bytes before the handler returns", func() {
+		inv := invoke("/events")
+		body := bufio.NewReader(inv.body)
+
+		prelude := readPrelude(body)
+		Expect(prelude["statusCode"]).To(BeEquivalentTo(200))
+		Expect(prelude["headers"]).To(HaveKeyWithValue("Content-Type", "text/event-stream"))
+		Expect(prelude["cookies"]).To(ConsistOf("session=abc"))
+
+		line, err := body.ReadString('\n')
+		Expect(err).To(BeNil())
+		Expect(line).To(Equal("data: one\n"))
+
+		close(release)
+		rest, err := io.ReadAll(body)
+		Expect(err).To(BeNil())
+		Expect(string(rest)).To(Equal("\ndata: two\n\n"))
+	})
+
+	It("commits the status code when the handler writes no body", func() {
+		inv := invoke("/missing")
+		body := bufio.NewReader(inv.body)
+
+		prelude := readPrelude(body)
+		Expect(prelude["statusCode"]).To(BeEquivalentTo(404))
+		rest, err := io.ReadAll(body)
+		Expect(err).To(BeNil())
+		Expect(rest).To(BeEmpty())
+	})
+
+	It("aborts the stream when the handler panics", func() {
+		inv := invoke("/panic")
+		body := bufio.NewReader(inv.body)
+
+		prelude := readPrelude(body)
+		Expect(prelude["statusCode"]).To(BeEquivalentTo(500))
+		_, err := io.ReadAll(body)
+		Expect(err).ToNot(BeNil())
+	})
+})
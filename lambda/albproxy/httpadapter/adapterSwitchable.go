@@ -0,0 +1,85 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerAdapterSwitchable serves the same http.Handler behind ALB, API Gateway REST (v1)
+// and API Gateway HTTP API (v2) / Function URLs. The event type is detected from the raw
+// payload and the response is returned in the format matching the detected request.
+type HandlerAdapterSwitchable struct {
+	alb *HandlerAdapterALB
+	v1  *HandlerAdapter
+	v2  *HandlerAdapterV2
+}
+
+func NewSwitchable(handler http.Handler, opts ...Option) *HandlerAdapterSwitchable {
+	c := newConfig(opts)
+	c.warnUnusedHooks("switchable",
+		hooksFor[events.ALBTargetGroupRequest, events.ALBTargetGroupResponse](),
+		hooksFor[events.APIGatewayProxyRequest, events.APIGatewayProxyResponse](),
+		hooksFor[events.APIGatewayV2HTTPRequest, events.APIGatewayV2HTTPResponse]())
+	return &HandlerAdapterSwitchable{
+		alb: &HandlerAdapterALB{handler: handler, config: c},
+		v1:  &HandlerAdapter{handler: handler, config: c},
+		v2:  &HandlerAdapterV2{handler: handler, config: c},
+	}
+}
+
+// StripBasePath sets the base path to be removed from the request path for all event types.
+func (h *HandlerAdapterSwitchable) StripBasePath(basePath string) string {
+	h.alb.StripBasePath(basePath)
+	h.v1.StripBasePath(basePath)
+	return h.v2.StripBasePath(basePath)
+}
+
+// StripBasePaths sets several base paths to be removed from the request path for all
+// event types, see core.RequestAccessor.StripBasePaths.
+func (h *HandlerAdapterSwitchable) StripBasePaths(basePaths ...string) {
+	h.alb.StripBasePaths(basePaths...)
+	h.v1.StripBasePaths(basePaths...)
+	h.v2.StripBasePaths(basePaths...)
+}
+
+// DeriveBasePath enables or disables removing the base path reported by API Gateway
+// events, see core.RequestAccessor.DeriveBasePath.
+func (h *HandlerAdapterSwitchable) DeriveBasePath(enabled bool) {
+	h.alb.DeriveBasePath(enabled)
+	h.v1.DeriveBasePath(enabled)
+	h.v2.DeriveBasePath(enabled)
+}
+
+// ProxyWithContext receives context and a raw proxy event, detects whether it is an ALB,
+// API Gateway v1 or API Gateway v2 event and sends it to the matching adapter.
+// It returns the response in the format of the detected event.
+func (h *HandlerAdapterSwitchable) ProxyWithContext(ctx context.Context, event json.RawMessage) (*core.SwitchableResponse, error) {
+	var req core.SwitchableRequest
+	if err := json.Unmarshal(event, &req); err != nil {
+		appLog.Error("Could not determine proxy event type", "err", err)
+		return nil, core.NewLoggedError("Could not determine proxy event type: %v", err)
+	}
+	return h.ProxySwitchableWithContext(ctx, &req)
+}
+
+// ProxySwitchableWithContext sends an already decoded SwitchableRequest to the matching adapter.
+func (h *HandlerAdapterSwitchable) ProxySwitchableWithContext(ctx context.Context, req *core.SwitchableRequest) (*core.SwitchableResponse, error) {
+	if e := req.ALB(); e != nil {
+		resp, err := h.alb.ProxyWithContext(ctx, *e)
+		return core.NewSwitchableResponseALB(&resp), err
+	}
+	if e := req.Version1(); e != nil {
+		resp, err := h.v1.ProxyWithContext(ctx, *e)
+		return core.NewSwitchableResponseV1(&resp), err
+	}
+	if e := req.Version2(); e != nil {
+		resp, err := h.v2.ProxyWithContext(ctx, *e)
+		return core.NewSwitchableResponseV2(&resp), err
+	}
+	return nil, core.NewLoggedError("Empty switchable request")
+}
@@ -0,0 +1,134 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandlerAdapterWebsocket serves API Gateway WebSocket APIs. Route keys are mapped to
+// http.Handlers, see core.RequestAccessorWebsocket for the synthetic requests, and the
+// open connections are kept in a ConnectionRegistry.
+//
+// A connection is saved when $connect succeeds, with the email, name, phone and role
+// values of the authorizer context, and deleted on $disconnect. Handlers for $connect
+// and $disconnect are optional; $connect is accepted when there is none. Messages with
+// a route key without handler go to the $default handler, or get a 404.
+type HandlerAdapterWebsocket struct {
+	core.RequestAccessorWebsocket
+	routes   map[string]http.Handler
+	registry ConnectionRegistry
+	config
+}
+
+func NewWebsocket(registry ConnectionRegistry, opts ...Option) *HandlerAdapterWebsocket {
+	h := &HandlerAdapterWebsocket{
+		routes:   make(map[string]http.Handler),
+		registry: registry,
+		config:   newConfig(opts),
+	}
+	h.warnUnusedHooks("WebSocket", hooksFor[events.APIGatewayWebsocketProxyRequest, events.APIGatewayProxyResponse]())
+	return h
+}
+
+// Handle registers the handler for a route key such as $connect or sendmessage.
+func (h *HandlerAdapterWebsocket) Handle(routeKey string, handler http.Handler) {
+	h.routes[routeKey] = handler
+}
+
+// ProxyWithContext receives context and an API Gateway WebSocket event, sends it to the
+// handler of its route key and keeps the connection registry up to date.
+// The response body of message routes is returned to the client as the route response.
+func (h *HandlerAdapterWebsocket) ProxyWithContext(ctx context.Context, event events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyWithContext)
+}
+
+func (h *HandlerAdapterWebsocket) proxyWithContext(ctx context.Context, event events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	rc := event.RequestContext
+	appLog.Debug("Received WebSocket Event", "routeKey", rc.RouteKey, "eventType", rc.EventType, "connectionId", rc.ConnectionID)
+
+	req, err := h.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert WebSocket event to request", "connectionId", rc.ConnectionID, "err", err)
+		return core.InternalServerError(), core.NewLoggedError("Could not convert WebSocket event to request: %v", err)
+	}
+
+	inv := startInvocation()
+	defer func() {
+		h.finishInvocation(inv, nil, "routeKey", rc.RouteKey)
+	}()
+
+	resp := events.APIGatewayProxyResponse{StatusCode: http.StatusOK}
+	if handler := h.handler(rc.RouteKey); handler != nil {
+		resp, err = h.serveRoute(handler, req)
+		if err != nil {
+			return resp, err
+		}
+	} else if rc.RouteKey != core.WebsocketConnectRoute && rc.RouteKey != core.WebsocketDisconnectRoute {
+		appLog.Info("No handler for WebSocket route", "routeKey", rc.RouteKey, "connectionId", rc.ConnectionID)
+		resp = events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: http.StatusText(http.StatusNotFound)}
+	}
+
+	switch rc.RouteKey {
+	case core.WebsocketConnectRoute:
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			appLog.Info("WebSocket connection rejected", "connectionId", rc.ConnectionID, "status", resp.StatusCode)
+			break
+		}
+		if err := h.registry.Save(ctx, websocketConnection(rc)); err != nil {
+			appLog.Error("Could not save WebSocket connection", "connectionId", rc.ConnectionID, "err", err)
+			return core.InternalServerError(), nil
+		}
+	case core.WebsocketDisconnectRoute:
+		if err := h.registry.Delete(ctx, rc.ConnectionID); err != nil {
+			appLog.Error("Could not delete WebSocket connection", "connectionId", rc.ConnectionID, "err", err)
+		}
+	}
+	return resp, nil
+}
+
+func (h *HandlerAdapterWebsocket) handler(routeKey string) http.Handler {
+	if handler, ok := h.routes[routeKey]; ok {
+		return handler
+	}
+	if routeKey == core.WebsocketConnectRoute || routeKey == core.WebsocketDisconnectRoute {
+		return nil
+	}
+	return h.routes[core.WebsocketDefaultRoute]
+}
+
+func (h *HandlerAdapterWebsocket) serveRoute(handler http.Handler, req *http.Request) (events.APIGatewayProxyResponse, error) {
+	w := core.NewProxyResponseWriter()
+	w.SetPayloadOptions(h.payloadOptions(req))
+	if !h.serve(handler, http.ResponseWriter(w), req) {
+		return core.GatewayTimeout(), nil
+	}
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		appLog.Error("Error while generating WebSocket response", "err", err)
+		return core.GatewayTimeout(), core.NewLoggedError("Error while generating WebSocket response: %v", err)
+	}
+	return resp, nil
+}
+
+// websocketConnection builds the registry entry of a new connection.
+func websocketConnection(rc events.APIGatewayWebsocketProxyRequestContext) WebsocketConnection {
+	connectedAt := time.Now()
+	if rc.ConnectedAt > 0 {
+		connectedAt = time.UnixMilli(rc.ConnectedAt)
+	}
+	return WebsocketConnection{
+		ConnectionID: rc.ConnectionID,
+		Email:        core.WebsocketAuthorizerValue(rc, core.AuthorizerEmailKey),
+		Name:         core.WebsocketAuthorizerValue(rc, core.AuthorizerNameKey),
+		Phone:        core.WebsocketAuthorizerValue(rc, core.AuthorizerPhoneKey),
+		Role:         core.WebsocketAuthorizerValue(rc, core.AuthorizerRoleKey),
+		CallbackURL:  core.WebsocketCallbackURL(rc),
+		ConnectedAt:  connectedAt,
+	}
+}
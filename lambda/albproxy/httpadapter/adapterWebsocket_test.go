@@ -0,0 +1,185 @@
+package httpadapter_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func websocketEvent(routeKey, connectionID, body string) events.APIGatewayWebsocketProxyRequest {
+	return events.APIGatewayWebsocketProxyRequest{
+		Body: body,
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			RouteKey:     routeKey,
+			ConnectionID: connectionID,
+			DomainName:   "abc.execute-api.ap-south-1.amazonaws.com",
+			Stage:        "prod",
+			ConnectedAt:  1700000000000,
+			Authorizer: map[string]interface{}{
+				"email": "a@example.com",
+				"name":  "A",
+				"role":  "admin",
+			},
+		},
+	}
+}
+
+var _ = Describe("HandlerAdapterWebsocket", func() {
+	var (
+		adapter  *httpadapter.HandlerAdapterWebsocket
+		registry *httpadapter.ConnectionRegistryMemory
+		ctx      = context.Background()
+	)
+
+	BeforeEach(func() {
+		registry = httpadapter.NewConnectionRegistryMemory()
+		adapter = httpadapter.NewWebsocket(registry)
+		adapter.Handle("sendmessage", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/sendmessage"))
+			rc, ok := core.GetWebsocketContextFromContext(r.Context())
+			Expect(ok).To(BeTrue())
+			Expect(r.Header.Get(core.WebsocketConnectionIDHeader)).To(Equal(rc.ConnectionID))
+			w.Write(append([]byte("echo:"), body...))
+		}))
+	})
+
+	It("saves the connection with the authorizer identity on $connect", func() {
+		resp, err := adapter.ProxyWithContext(ctx, websocketEvent(core.WebsocketConnectRoute, "c1", ""))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		conn, err := registry.Get(ctx, "c1")
+		Expect(err).To(BeNil())
+		Expect(conn.Email).To(Equal("a@example.com"))
+		Expect(conn.Role).To(Equal("admin"))
+		Expect(conn.CallbackURL).To(Equal("https://abc.execute-api.ap-south-1.amazonaws.com/prod"))
+		Expect(conn.ConnectedAt.UnixMilli()).To(Equal(int64(1700000000000)))
+	})
+
+	It("does not save rejected connections", func() {
+		adapter.Handle(core.WebsocketConnectRoute, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodGet))
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}))
+		resp, err := adapter.ProxyWithContext(ctx, websocketEvent(core.WebsocketConnectRoute, "c1", ""))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		_, err = registry.Get(ctx, "c1")
+		Expect(err).To(Equal(httpadapter.ErrConnectionNotFound))
+	})
+
+	It("deletes the connection on $disconnect", func() {
+		_, err := adapter.ProxyWithContext(ctx, websocketEvent(core.WebsocketConnectRoute, "c1", ""))
+		Expect(err).To(BeNil())
+		_, err = adapter.ProxyWithContext(ctx, websocketEvent(core.WebsocketDisconnectRoute, "c1", ""))
+		Expect(err).To(BeNil())
+		_, err = registry.Get(ctx, "c1")
+		Expect(err).To(Equal(httpadapter.ErrConnectionNotFound))
+	})
+
+	It("returns the handler response to the client on custom routes", func() {
+		resp, err := adapter.ProxyWithContext(ctx, websocketEvent("sendmessage", "c1", "hi"))
+		Expect(err).To(BeNil())
+		Expect(resp.Body).To(Equal("echo:hi"))
+
+		resp, err = adapter.ProxyWithContext(ctx, websocketEvent("unknown", "c1", "hi"))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("ConnectionSender", func() {
+	ctx := context.Background()
+
+	It("broadcasts through the in-memory fake and forgets gone connections", func() {
+		registry := httpadapter.NewConnectionRegistryMemory()
+		sender := httpadapter.NewConnectionSenderMemory()
+		for _, id := range []string{"c1", "c2"} {
+			Expect(registry.Save(ctx, httpadapter.WebsocketConnection{ConnectionID: id, Role: "admin"})).To(Succeed())
+		}
+		sender.Disconnect("c2")
+
+		conns, err := registry.ByRole(ctx, "admin")
+		Expect(err).To(BeNil())
+		Expect(httpadapter.Broadcast(ctx, sender, registry, conns, []byte("update"))).To(Succeed())
+
+		Expect(sender.Messages("c1")).To(Equal([][]byte{[]byte("update")}))
+		_, err = registry.Get(ctx, "c2")
+		Expect(err).To(Equal(httpadapter.ErrConnectionNotFound))
+	})
+
+	It("posts signed requests to the management API", func() {
+		creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", SessionToken: "session-token"}
+		for k, v := range map[string]string{
+			"AWS_ACCESS_KEY_ID":     creds.AccessKeyID,
+			"AWS_SECRET_ACCESS_KEY": creds.SecretAccessKey,
+			"AWS_SESSION_TOKEN":     creds.SessionToken,
+			"AWS_REGION":            "ap-south-1",
+		} {
+			if old, ok := os.LookupEnv(k); ok {
+				defer os.Setenv(k, old)
+			} else {
+				defer os.Unsetenv(k)
+			}
+			os.Setenv(k, v)
+		}
+
+		var path, auth, expectedAuth, token, body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			path, auth, token, body = r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("X-Amz-Security-Token"), string(b)
+
+			// sign the signed headers of the request as received again, like API Gateway does
+			signed, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+			signed.ContentLength = r.ContentLength
+			_, signedHeaders, _ := strings.Cut(auth, "SignedHeaders=")
+			signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+			for _, name := range strings.Split(signedHeaders, ";") {
+				if v := r.Header.Get(name); v != "" {
+					signed.Header.Set(name, v)
+				}
+			}
+			signedAt, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+			hash := sha256.Sum256(b)
+			v4.NewSigner().SignHTTP(context.Background(), creds, signed, hex.EncodeToString(hash[:]), "execute-api", "ap-south-1", signedAt)
+			expectedAuth = signed.Header.Get("Authorization")
+
+			if strings.HasSuffix(path, "gone") {
+				w.WriteHeader(http.StatusGone)
+			}
+		}))
+		defer server.Close()
+
+		sender := httpadapter.NewConnectionSenderAPI(server.Client())
+		err := sender.Send(ctx, httpadapter.WebsocketConnection{ConnectionID: "abc=", CallbackURL: server.URL + "/prod"}, []byte("hello"))
+		Expect(err).To(BeNil())
+		Expect(path).To(Equal("/prod/@connections/abc%3D"))
+		Expect(auth).To(HavePrefix("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		Expect(auth).To(ContainSubstring("/ap-south-1/execute-api/aws4_request"))
+		Expect(auth).To(ContainSubstring("x-amz-security-token"))
+		Expect(auth).To(Equal(expectedAuth))
+		Expect(token).To(Equal("session-token"))
+		Expect(body).To(Equal("hello"))
+
+		err = sender.Send(ctx, httpadapter.WebsocketConnection{ConnectionID: "gone", CallbackURL: server.URL + "/prod"}, []byte("hello"))
+		Expect(err).To(Equal(httpadapter.ErrConnectionGone))
+	})
+})
@@ -0,0 +1,76 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// responseHandler answers with a status, a repeated header, two cookies and, for
+// /binary, a binary body.
+var responseHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("X-Tag", "a")
+	w.Header().Add("X-Tag", "b")
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+	if r.URL.Path == "/binary" {
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+})
+
+func v1Event(method, path string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: method,
+		Path:       path,
+		Headers:    map[string]string{"host": "example.com"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Stage:     "prod",
+			RequestID: "r1",
+		},
+	}
+}
+
+var _ = Describe("HandlerAdapter", func() {
+	adapter := httpadapter.New(responseHandler)
+
+	It("maps the status, headers, cookies and body of the handler response", func() {
+		for _, proxy := range []func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error){
+			adapter.Proxy,
+			func(e events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return adapter.ProxyWithContext(context.Background(), e)
+			},
+		} {
+			resp, err := proxy(v1Event("POST", "/orders"))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(resp.MultiValueHeaders).To(HaveKeyWithValue("X-Tag", []string{"a", "b"}))
+			Expect(resp.MultiValueHeaders).To(HaveKeyWithValue("Set-Cookie", []string{"session=abc", "theme=dark"}))
+			Expect(resp.MultiValueHeaders).To(HaveKeyWithValue("Content-Type", []string{"application/json"}))
+			Expect(resp.IsBase64Encoded).To(BeFalse())
+			Expect(resp.Body).To(Equal(`{"path":"/orders"}`))
+		}
+	})
+
+	It("encodes binary bodies in base64", func() {
+		resp, err := adapter.ProxyWithContext(context.Background(), v1Event("GET", "/binary"))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		body, err := base64.StdEncoding.DecodeString(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(body).To(Equal([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}))
+	})
+})
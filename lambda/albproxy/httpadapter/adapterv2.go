@@ -0,0 +1,78 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type HandlerAdapterV2 struct {
+	core.RequestAccessorV2
+	handler http.Handler
+	config
+}
+
+func NewV2(handler http.Handler, opts ...Option) *HandlerAdapterV2 {
+	h := &HandlerAdapterV2{
+		handler: handler,
+		config:  newConfig(opts),
+	}
+	h.warnUnusedHooks("API Gateway v2", hooksFor[events.APIGatewayV2HTTPRequest, events.APIGatewayV2HTTPResponse]())
+	return h
+}
+
+// Proxy receives an API Gateway HTTP API (v2) or Function URL event, transforms it into an http.Request
+// object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapterV2) Proxy(event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return intercept(&h.config, context.Background(), event, func(_ context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		req, err := h.ProxyEventToHTTPRequest(event)
+		return h.proxyInternal(req, err)
+	})
+}
+
+// ProxyWithContext receives context and an API Gateway HTTP API (v2) or Function URL event,
+// transforms them into an http.Request object, and sends it to the http.Handler for routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (h *HandlerAdapterV2) ProxyWithContext(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return intercept(&h.config, ctx, event, h.proxyWithContext)
+}
+
+func (h *HandlerAdapterV2) proxyWithContext(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	appLog.Debug("Received API Gateway V2 Request", "event", event)
+	req, err := h.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		appLog.Error("Could not convert proxy event to request", "event", event, "err", err)
+	} else {
+		appLog.Debug("Converted proxy event to request", "event", event, "header", req.Header, "method", req.Method, "URL", req.URL)
+	}
+	return h.proxyInternal(req, err)
+}
+
+func (h *HandlerAdapterV2) proxyInternal(req *http.Request, err error) (events.APIGatewayV2HTTPResponse, error) {
+	if err != nil {
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+
+	w := core.NewProxyResponseWriterV2()
+	w.SetPayloadOptions(h.payloadOptions(req))
+	inv := startInvocation()
+	if !h.serve(h.handler, http.ResponseWriter(w), req) {
+		h.finishInvocation(inv, nil, "method", req.Method, "path", req.URL.Path, "timeout", true)
+		return core.GatewayTimeoutV2(), nil
+	}
+	h.finishInvocation(inv, w.Header(), "method", req.Method, "path", req.URL.Path)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		appLog.Error("Error while generating proxy response", "err", err)
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Error while generating proxy response: %v", err)
+	} else {
+		appLog.Debug("Generated proxy response", "resp", resp)
+	}
+
+	return resp, nil
+}
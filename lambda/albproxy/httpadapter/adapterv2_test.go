@@ -0,0 +1,60 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func v2Event(method, path string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: path,
+		Headers: map[string]string{"host": "example.com"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			DomainName: "example.com",
+			HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: method},
+		},
+	}
+}
+
+var _ = Describe("HandlerAdapterV2", func() {
+	adapter := httpadapter.NewV2(responseHandler)
+
+	It("maps the status, headers, cookies and body of the handler response", func() {
+		for _, proxy := range []func(events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error){
+			adapter.Proxy,
+			func(e events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+				return adapter.ProxyWithContext(context.Background(), e)
+			},
+		} {
+			resp, err := proxy(v2Event("POST", "/orders"))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			// HTTP APIs take repeated headers comma separated and cookies in their own field
+			Expect(resp.Headers).To(HaveKeyWithValue("X-Tag", "a,b"))
+			Expect(resp.Headers).To(HaveKeyWithValue("Content-Type", "application/json"))
+			Expect(resp.Headers).ToNot(HaveKey("Set-Cookie"))
+			Expect(resp.Cookies).To(Equal([]string{"session=abc", "theme=dark"}))
+			Expect(resp.IsBase64Encoded).To(BeFalse())
+			Expect(resp.Body).To(Equal(`{"path":"/orders"}`))
+		}
+	})
+
+	It("encodes binary bodies in base64", func() {
+		resp, err := adapter.ProxyWithContext(context.Background(), v2Event("GET", "/binary"))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		body, err := base64.StdEncoding.DecodeString(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(body).To(Equal([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}))
+	})
+})
@@ -0,0 +1,210 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/web"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// AuthorizerOptions configures an Authorizer.
+type AuthorizerOptions struct {
+	// APIKey, when set, authorizes callers sending it in the X-API-KEY header like
+	// web.WithApiKey. For TOKEN authorizers the token itself is compared.
+	APIKey string
+
+	// TokenHeader is the header carrying the JWT of REQUEST authorizers,
+	// Authorization by default. A "Bearer " prefix is removed.
+	TokenHeader string
+
+	// VerifyToken checks the signature, issuer and audience of a JWT before its claims
+	// are trusted. Without it JWTs are rejected; pass a function returning nil only if
+	// the token was verified upstream, as AWS LB does for X-Amzn-Oidc-Data.
+	VerifyToken func(ctx context.Context, token string) error
+}
+
+// Authorizer is an API Gateway Lambda authorizer built on the same checks as the web
+// auth middleware. A caller is authorized by a matching API key or by a verified,
+// unexpired JWT whose claims are parsed like web.WithAlbAuth does.
+//
+// The email, name, phone and role of JWT callers are returned as authorizer context
+// values, see core.AuthorizerEmailKey, which the backend adapters put under the
+// web.UserEmail, web.UserName, web.UserPhone and web.AttmgtRole context keys.
+type Authorizer struct {
+	opts AuthorizerOptions
+}
+
+func NewAuthorizer(opts AuthorizerOptions) *Authorizer {
+	if opts.TokenHeader == "" {
+		opts.TokenHeader = "Authorization"
+	}
+	return &Authorizer{opts: opts}
+}
+
+// authorization is the outcome of checking a caller.
+type authorization struct {
+	allowed   bool
+	principal string
+	context   map[string]interface{}
+}
+
+// ProxyTokenWithContext handles a TOKEN authorizer event of a REST API and returns an IAM policy.
+func (a *Authorizer) ProxyTokenWithContext(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	auth := a.authorize(ctx, event.AuthorizationToken, event.AuthorizationToken)
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID:    auth.principal,
+		PolicyDocument: authorizerPolicy(auth.allowed, event.MethodArn),
+		Context:        auth.context,
+	}, nil
+}
+
+// ProxyRequestWithContext handles a REQUEST authorizer event of a REST or WebSocket API
+// and returns an IAM policy.
+func (a *Authorizer) ProxyRequestWithContext(ctx context.Context, event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	auth := a.authorize(ctx, authorizerHeader(event.Headers, web.ApiKeyHeader), authorizerHeader(event.Headers, a.opts.TokenHeader))
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID:    auth.principal,
+		PolicyDocument: authorizerPolicy(auth.allowed, event.MethodArn),
+		Context:        auth.context,
+	}, nil
+}
+
+// ProxyV2WithContext handles an HTTP API authorizer event (payload format 2.0) and
+// returns a simple response.
+func (a *Authorizer) ProxyV2WithContext(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	auth := a.authorize(ctx, authorizerHeader(event.Headers, web.ApiKeyHeader), authorizerHeader(event.Headers, a.opts.TokenHeader))
+	return events.APIGatewayV2CustomAuthorizerSimpleResponse{
+		IsAuthorized: auth.allowed,
+		Context:      auth.context,
+	}, nil
+}
+
+// ProxyV2IAMWithContext handles an HTTP API authorizer event (payload format 2.0) and
+// returns an IAM policy.
+func (a *Authorizer) ProxyV2IAMWithContext(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerIAMPolicyResponse, error) {
+	auth := a.authorize(ctx, authorizerHeader(event.Headers, web.ApiKeyHeader), authorizerHeader(event.Headers, a.opts.TokenHeader))
+	return events.APIGatewayV2CustomAuthorizerIAMPolicyResponse{
+		PrincipalID:    auth.principal,
+		PolicyDocument: authorizerPolicy(auth.allowed, event.RouteArn),
+		Context:        auth.context,
+	}, nil
+}
+
+func (a *Authorizer) authorize(ctx context.Context, apiKey, token string) authorization {
+	if a.opts.APIKey != "" && apiKey != "" {
+		if web.ValidApiKey(apiKey, a.opts.APIKey) {
+			return authorization{allowed: true, principal: "api-key"}
+		}
+	}
+
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		appLog.Info("Authorizer denied request without valid credentials")
+		return authorization{principal: "anonymous"}
+	}
+
+	claims, err := a.verify(ctx, token)
+	if err != nil {
+		appLog.Info("Authorizer denied token", "err", err)
+		return authorization{principal: "anonymous"}
+	}
+
+	principal := claims.Sub
+	if principal == "" {
+		principal = claims.Email
+	}
+	return authorization{
+		allowed:   true,
+		principal: principal,
+		context: map[string]interface{}{
+			core.AuthorizerEmailKey: claims.Email,
+			core.AuthorizerNameKey:  claims.Name,
+			core.AuthorizerPhoneKey: claims.PhoneNumber,
+			core.AuthorizerRoleKey:  claims.AttmgtRole,
+		},
+	}
+}
+
+func (a *Authorizer) verify(ctx context.Context, token string) (web.OidcClaims, error) {
+	if a.opts.VerifyToken == nil {
+		return web.OidcClaims{}, errors.New("no VerifyToken configured")
+	}
+	if err := a.opts.VerifyToken(ctx, token); err != nil {
+		return web.OidcClaims{}, err
+	}
+
+	claims, err := web.ParseOidcClaims(token)
+	if err != nil {
+		return claims, err
+	}
+	if claims.Exp > 0 && time.Unix(int64(claims.Exp), 0).Before(time.Now()) {
+		return claims, errors.New("token expired")
+	}
+	return claims, nil
+}
+
+// authorizerPolicy allows or denies invoking the API. Allow policies cover the whole
+// stage, arn:...:api/stage/*, so a cached policy is valid for every route.
+func authorizerPolicy(allowed bool, arn string) events.APIGatewayCustomAuthorizerPolicy {
+	effect, resource := "Deny", arn
+	if allowed {
+		effect = "Allow"
+		if parts := strings.SplitN(arn, "/", 3); len(parts) == 3 {
+			resource = parts[0] + "/" + parts[1] + "/*"
+		}
+	}
+	return events.APIGatewayCustomAuthorizerPolicy{
+		Version: "2012-10-17",
+		Statement: []events.IAMPolicyStatement{{
+			Action:   []string{"execute-api:Invoke"},
+			Effect:   effect,
+			Resource: []string{resource},
+		}},
+	}
+}
+
+// authorizerHeader looks up a header regardless of the case API Gateway delivered it in.
+func authorizerHeader(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// withAuthorizerUser puts the user values of the Lambda authorizer context of req, see
+// core.GetAuthorizerFromContext, under the web context keys (web.UserEmail,
+// web.AttmgtRole, ...), so handlers read them the same way as behind web.WithAlbAuth.
+// Requests without any of the values are returned unchanged.
+func withAuthorizerUser(req *http.Request) *http.Request {
+	authorizer, ok := core.GetAuthorizerFromContext(req.Context())
+	if !ok {
+		return req
+	}
+	_, email := authorizer[core.AuthorizerEmailKey]
+	_, name := authorizer[core.AuthorizerNameKey]
+	_, phone := authorizer[core.AuthorizerPhoneKey]
+	_, role := authorizer[core.AuthorizerRoleKey]
+	if !email && !name && !phone && !role {
+		return req
+	}
+	return req.WithContext(web.WithUser(req.Context(),
+		core.AuthorizerValue(authorizer, core.AuthorizerEmailKey),
+		core.AuthorizerValue(authorizer, core.AuthorizerNameKey),
+		core.AuthorizerValue(authorizer, core.AuthorizerPhoneKey),
+		core.AuthorizerValue(authorizer, core.AuthorizerRoleKey),
+	))
+}
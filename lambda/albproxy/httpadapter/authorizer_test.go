@@ -0,0 +1,138 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+	"github.com/rsingh25/tukashi-lib/web"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// testToken builds an unsigned JWT carrying the claims.
+func testToken(claims web.OidcClaims) string {
+	payload, err := json.Marshal(claims)
+	Expect(err).To(BeNil())
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+var _ = Describe("Authorizer", func() {
+	var (
+		authorizer *httpadapter.Authorizer
+		ctx        = context.Background()
+		methodArn  = "arn:aws:execute-api:ap-south-1:000000000000:abc123/prod/GET/attendance"
+		claims     = web.OidcClaims{Sub: "u1", Email: "a@example.com", Name: "A", PhoneNumber: "+100", AttmgtRole: "admin"}
+	)
+
+	BeforeEach(func() {
+		authorizer = httpadapter.NewAuthorizer(httpadapter.AuthorizerOptions{
+			APIKey: "secret",
+			VerifyToken: func(ctx context.Context, token string) error {
+				if token == "forged" {
+					return errors.New("bad signature")
+				}
+				return nil
+			},
+		})
+	})
+
+	It("allows verified tokens with the user in the context", func() {
+		resp, err := authorizer.ProxyRequestWithContext(ctx, events.APIGatewayCustomAuthorizerRequestTypeRequest{
+			MethodArn: methodArn,
+			Headers:   map[string]string{"authorization": "Bearer " + testToken(claims)},
+		})
+		Expect(err).To(BeNil())
+		Expect(resp.PrincipalID).To(Equal("u1"))
+		Expect(resp.PolicyDocument.Statement[0].Effect).To(Equal("Allow"))
+		Expect(resp.PolicyDocument.Statement[0].Resource).To(Equal([]string{"arn:aws:execute-api:ap-south-1:000000000000:abc123/prod/*"}))
+		Expect(resp.Context).To(Equal(map[string]interface{}{"email": "a@example.com", "name": "A", "phone": "+100", "role": "admin"}))
+	})
+
+	It("denies missing, forged and expired tokens", func() {
+		expired := claims
+		expired.Exp = int(time.Now().Add(-time.Hour).Unix())
+		for _, token := range []string{"", "forged", testToken(expired)} {
+			resp, err := authorizer.ProxyTokenWithContext(ctx, events.APIGatewayCustomAuthorizerRequest{AuthorizationToken: token, MethodArn: methodArn})
+			Expect(err).To(BeNil())
+			Expect(resp.PolicyDocument.Statement[0].Effect).To(Equal("Deny"))
+			Expect(resp.PolicyDocument.Statement[0].Resource).To(Equal([]string{methodArn}))
+		}
+	})
+
+	It("rejects tokens when no verifier is configured", func() {
+		resp, err := httpadapter.NewAuthorizer(httpadapter.AuthorizerOptions{}).ProxyV2WithContext(ctx, events.APIGatewayV2CustomAuthorizerV2Request{
+			Headers: map[string]string{"authorization": testToken(claims)},
+		})
+		Expect(err).To(BeNil())
+		Expect(resp.IsAuthorized).To(BeFalse())
+	})
+
+	It("allows api keys without user context", func() {
+		resp, err := authorizer.ProxyV2WithContext(ctx, events.APIGatewayV2CustomAuthorizerV2Request{
+			Headers: map[string]string{"x-api-key": "secret"},
+		})
+		Expect(err).To(BeNil())
+		Expect(resp.IsAuthorized).To(BeTrue())
+		Expect(resp.Context).To(BeNil())
+
+		resp, err = authorizer.ProxyV2WithContext(ctx, events.APIGatewayV2CustomAuthorizerV2Request{
+			Headers: map[string]string{"x-api-key": "wrong"},
+		})
+		Expect(err).To(BeNil())
+		Expect(resp.IsAuthorized).To(BeFalse())
+	})
+
+	Context("on the backend", func() {
+		var user []interface{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			user = []interface{}{ctx.Value(web.UserEmail{}), ctx.Value(web.UserName{}), ctx.Value(web.UserPhone{}), ctx.Value(web.AttmgtRole{})}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		BeforeEach(func() {
+			user = nil
+		})
+
+		It("puts REST API authorizer values under the web context keys", func() {
+			_, err := httpadapter.New(handler).ProxyWithContext(ctx, events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Path:       "/",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{"email": "a@example.com", "name": "A", "phone": "+100", "role": "admin", "principalId": "u1"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(user).To(Equal([]interface{}{"a@example.com", "A", "+100", "admin"}))
+		})
+
+		It("puts HTTP API authorizer values under the web context keys", func() {
+			_, err := httpadapter.NewV2(handler).ProxyWithContext(ctx, events.APIGatewayV2HTTPRequest{
+				RawPath: "/",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+					Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+						Lambda: map[string]interface{}{core.AuthorizerEmailKey: "a@example.com", core.AuthorizerRoleKey: "admin"},
+					},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(user).To(Equal([]interface{}{"a@example.com", "", "", "admin"}))
+		})
+
+		It("leaves the context alone without authorizer values", func() {
+			_, err := httpadapter.New(handler).ProxyWithContext(ctx, events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/"})
+			Expect(err).To(BeNil())
+			Expect(user).To(Equal([]interface{}{nil, nil, nil, nil}))
+		})
+	})
+})
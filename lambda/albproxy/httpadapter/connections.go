@@ -0,0 +1,215 @@
+package httpadapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/database"
+)
+
+// ErrConnectionNotFound is returned by a ConnectionRegistry for unknown connection IDs.
+var ErrConnectionNotFound = errors.New("websocket connection not found")
+
+// WebsocketConnection is an open WebSocket connection and the identity of its user.
+type WebsocketConnection struct {
+	ConnectionID string
+	Email        string
+	Name         string
+	Phone        string
+	Role         string
+	CallbackURL  string
+	ConnectedAt  time.Time
+}
+
+// ConnectionRegistry keeps the open WebSocket connections and the identity of their users.
+type ConnectionRegistry interface {
+	// Save adds or replaces a connection.
+	Save(ctx context.Context, conn WebsocketConnection) error
+
+	// Delete removes a connection. Deleting an unknown connection is not an error.
+	Delete(ctx context.Context, connectionID string) error
+
+	// Get returns a connection or ErrConnectionNotFound.
+	Get(ctx context.Context, connectionID string) (WebsocketConnection, error)
+
+	// ByEmail returns the connections of a user, oldest first.
+	ByEmail(ctx context.Context, email string) ([]WebsocketConnection, error)
+
+	// ByRole returns the connections of all users with a role, oldest first.
+	ByRole(ctx context.Context, role string) ([]WebsocketConnection, error)
+}
+
+// WebsocketConnectionsSchema creates the table of NewConnectionRegistryDB. The registry
+// does not create it; add it to the migrations of the application.
+const WebsocketConnectionsSchema = `CREATE TABLE IF NOT EXISTS websocket_connections (
+    connection_id TEXT PRIMARY KEY,
+    email         TEXT NOT NULL DEFAULT '',
+    name          TEXT NOT NULL DEFAULT '',
+    phone         TEXT NOT NULL DEFAULT '',
+    role          TEXT NOT NULL DEFAULT '',
+    callback_url  TEXT NOT NULL,
+    connected_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS websocket_connections_email_idx ON websocket_connections (email);
+CREATE INDEX IF NOT EXISTS websocket_connections_role_idx ON websocket_connections (role);
+`
+
+const (
+	upsertConnection = `INSERT INTO websocket_connections (connection_id, email, name, phone, role, callback_url, connected_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (connection_id) DO UPDATE
+SET email = EXCLUDED.email, name = EXCLUDED.name, phone = EXCLUDED.phone, role = EXCLUDED.role,
+    callback_url = EXCLUDED.callback_url, connected_at = EXCLUDED.connected_at`
+	deleteConnection   = `DELETE FROM websocket_connections WHERE connection_id = $1`
+	selectConnections  = `SELECT connection_id, email, name, phone, role, callback_url, connected_at FROM websocket_connections `
+	getConnection      = selectConnections + `WHERE connection_id = $1`
+	connectionsByEmail = selectConnections + `WHERE email = $1 ORDER BY connected_at`
+	connectionsByRole  = selectConnections + `WHERE role = $1 ORDER BY connected_at`
+)
+
+// connectionRegistryDB stores connections in the websocket_connections table,
+// see WebsocketConnectionsSchema.
+type connectionRegistryDB struct {
+	db database.Service
+}
+
+// NewConnectionRegistryDB returns a ConnectionRegistry backed by the websocket_connections
+// table of db, see WebsocketConnectionsSchema. db is usually database.NewService().
+func NewConnectionRegistryDB(db database.Service) ConnectionRegistry {
+	return &connectionRegistryDB{db: db}
+}
+
+// inTx runs fn in a transaction of the service, as it exposes its connection pool
+// through transactions only. The transaction is committed when fn succeeds.
+func (r *connectionRegistryDB) inTx(ctx context.Context, readOnly bool, fn func(tx *sql.Tx) error) error {
+	tx, _, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *connectionRegistryDB) Save(ctx context.Context, conn WebsocketConnection) error {
+	return r.inTx(ctx, false, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, upsertConnection,
+			conn.ConnectionID, conn.Email, conn.Name, conn.Phone, conn.Role, conn.CallbackURL, conn.ConnectedAt)
+		return err
+	})
+}
+
+func (r *connectionRegistryDB) Delete(ctx context.Context, connectionID string) error {
+	return r.inTx(ctx, false, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, deleteConnection, connectionID)
+		return err
+	})
+}
+
+func (r *connectionRegistryDB) Get(ctx context.Context, connectionID string) (WebsocketConnection, error) {
+	var conn WebsocketConnection
+	err := r.inTx(ctx, true, func(tx *sql.Tx) error {
+		return scanConnection(tx.QueryRowContext(ctx, getConnection, connectionID), &conn)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return conn, ErrConnectionNotFound
+	}
+	return conn, err
+}
+
+func (r *connectionRegistryDB) ByEmail(ctx context.Context, email string) ([]WebsocketConnection, error) {
+	return r.list(ctx, connectionsByEmail, email)
+}
+
+func (r *connectionRegistryDB) ByRole(ctx context.Context, role string) ([]WebsocketConnection, error) {
+	return r.list(ctx, connectionsByRole, role)
+}
+
+func (r *connectionRegistryDB) list(ctx context.Context, query string, args ...interface{}) ([]WebsocketConnection, error) {
+	var conns []WebsocketConnection
+	err := r.inTx(ctx, true, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var conn WebsocketConnection
+			if err := scanConnection(rows, &conn); err != nil {
+				return err
+			}
+			conns = append(conns, conn)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+func scanConnection(row interface{ Scan(...interface{}) error }, conn *WebsocketConnection) error {
+	return row.Scan(&conn.ConnectionID, &conn.Email, &conn.Name, &conn.Phone, &conn.Role, &conn.CallbackURL, &conn.ConnectedAt)
+}
+
+// ConnectionRegistryMemory is an in-memory ConnectionRegistry for tests and local runs.
+type ConnectionRegistryMemory struct {
+	mu    sync.Mutex
+	conns map[string]WebsocketConnection
+}
+
+func NewConnectionRegistryMemory() *ConnectionRegistryMemory {
+	return &ConnectionRegistryMemory{conns: make(map[string]WebsocketConnection)}
+}
+
+func (r *ConnectionRegistryMemory) Save(ctx context.Context, conn WebsocketConnection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conns[conn.ConnectionID] = conn
+	return nil
+}
+
+func (r *ConnectionRegistryMemory) Delete(ctx context.Context, connectionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.conns, connectionID)
+	return nil
+}
+
+func (r *ConnectionRegistryMemory) Get(ctx context.Context, connectionID string) (WebsocketConnection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn, ok := r.conns[connectionID]
+	if !ok {
+		return conn, ErrConnectionNotFound
+	}
+	return conn, nil
+}
+
+func (r *ConnectionRegistryMemory) ByEmail(ctx context.Context, email string) ([]WebsocketConnection, error) {
+	return r.filter(func(conn WebsocketConnection) bool { return conn.Email == email }), nil
+}
+
+func (r *ConnectionRegistryMemory) ByRole(ctx context.Context, role string) ([]WebsocketConnection, error) {
+	return r.filter(func(conn WebsocketConnection) bool { return conn.Role == role }), nil
+}
+
+func (r *ConnectionRegistryMemory) filter(match func(WebsocketConnection) bool) []WebsocketConnection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var conns []WebsocketConnection
+	for _, conn := range r.conns {
+		if match(conn) {
+			conns = append(conns, conn)
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].ConnectedAt.Before(conns[j].ConnectedAt) })
+	return conns
+}
@@ -0,0 +1,170 @@
+package httpadapter_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/database"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// recordingDriver is a database/sql driver that records the statements and transactions of
+// a connection and answers queries with canned rows.
+type recordingDriver struct {
+	mu   sync.Mutex
+	log  []string
+	args [][]driver.Value
+	rows [][]driver.Value
+}
+
+func (d *recordingDriver) record(entry string, args []driver.NamedValue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, entry)
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	d.args = append(d.args, values)
+}
+
+func (d *recordingDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return recordingConn{d}, nil
+}
+func (d *recordingDriver) Driver() driver.Driver { return nil }
+
+type recordingConn struct{ db *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.db.record("BEGIN READ ONLY", nil)
+	} else {
+		c.db.record("BEGIN", nil)
+	}
+	return recordingTx{c.db}, nil
+}
+
+func (c recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query, args)
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return &recordingRows{rows: c.db.rows}, nil
+}
+
+type recordingTx struct{ db *recordingDriver }
+
+func (t recordingTx) Commit() error   { t.db.record("COMMIT", nil); return nil }
+func (t recordingTx) Rollback() error { t.db.record("ROLLBACK", nil); return nil }
+
+type recordingRows struct{ rows [][]driver.Value }
+
+func (r *recordingRows) Columns() []string {
+	return []string{"connection_id", "email", "name", "phone", "role", "callback_url", "connected_at"}
+}
+func (r *recordingRows) Close() error { return nil }
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// recordingService is a database.Service on a recordingDriver, where fakeDB has no
+// connection at all.
+type recordingService struct{ db *sql.DB }
+
+func (s recordingService) Health() map[string]string  { return map[string]string{"status": "up"} }
+func (s recordingService) Close() error               { return s.db.Close() }
+func (s recordingService) Queries() *database.Queries { return database.New(s.db) }
+func (s recordingService) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, *database.Queries, error) {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, database.New(s.db).WithTx(tx), nil
+}
+
+var _ = Describe("ConnectionRegistryDB", func() {
+	var (
+		fake     *recordingDriver
+		db       *sql.DB
+		registry httpadapter.ConnectionRegistry
+		ctx      = context.Background()
+		at       = time.UnixMilli(1700000000000).UTC()
+	)
+
+	BeforeEach(func() {
+		fake = &recordingDriver{}
+		db = sql.OpenDB(fake)
+		registry = httpadapter.NewConnectionRegistryDB(recordingService{db})
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("upserts a connection in a committed transaction", func() {
+		err := registry.Save(ctx, httpadapter.WebsocketConnection{
+			ConnectionID: "c1", Email: "a@example.com", Name: "A", Phone: "1", Role: "admin",
+			CallbackURL: "https://abc.execute-api.ap-south-1.amazonaws.com/prod", ConnectedAt: at,
+		})
+		Expect(err).To(BeNil())
+		Expect(fake.log).To(HaveLen(3))
+		Expect(fake.log[0]).To(Equal("BEGIN"))
+		Expect(fake.log[1]).To(HavePrefix("INSERT INTO websocket_connections"))
+		Expect(fake.log[1]).To(ContainSubstring("ON CONFLICT (connection_id) DO UPDATE"))
+		Expect(fake.args[1]).To(Equal([]driver.Value{"c1", "a@example.com", "A", "1", "admin",
+			"https://abc.execute-api.ap-south-1.amazonaws.com/prod", at}))
+		Expect(fake.log[2]).To(Equal("COMMIT"))
+	})
+
+	It("lists the connections of a role in a read only transaction", func() {
+		fake.rows = [][]driver.Value{
+			{"c1", "a@example.com", "A", "", "admin", "https://cb/prod", at},
+			{"c2", "b@example.com", "B", "", "admin", "https://cb/prod", at.Add(time.Second)},
+		}
+		conns, err := registry.ByRole(ctx, "admin")
+		Expect(err).To(BeNil())
+		Expect(conns).To(HaveLen(2))
+		Expect(conns[0].ConnectionID).To(Equal("c1"))
+		Expect(conns[1].Email).To(Equal("b@example.com"))
+		Expect(conns[1].ConnectedAt).To(Equal(at.Add(time.Second)))
+
+		Expect(fake.log[0]).To(Equal("BEGIN READ ONLY"))
+		Expect(strings.HasSuffix(fake.log[1], "WHERE role = $1 ORDER BY connected_at")).To(BeTrue())
+		Expect(fake.args[1]).To(Equal([]driver.Value{"admin"}))
+		Expect(fake.log[2]).To(Equal("COMMIT"))
+	})
+
+	It("rolls back and reports unknown connections as not found", func() {
+		_, err := registry.Get(ctx, "missing")
+		Expect(err).To(Equal(httpadapter.ErrConnectionNotFound))
+		Expect(fake.log).To(HaveLen(3))
+		Expect(fake.log[1]).To(HaveSuffix("WHERE connection_id = $1"))
+		Expect(fake.log[2]).To(Equal("ROLLBACK"))
+	})
+})
@@ -0,0 +1,190 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DefaultEmulatorTargetGroupArn is the target group ARN reported by EmulatorALB
+// when none is configured.
+const DefaultEmulatorTargetGroupArn = "arn:aws:elasticloadbalancing:local:000000000000:targetgroup/emulator/0000000000000000"
+
+// EmulatorALB is an http.Handler that emulates an Application Load Balancer in front of
+// a Lambda target. Each incoming request is converted into an events.ALBTargetGroupRequest,
+// sent through HandlerAdapterALB and the events.ALBTargetGroupResponse is written back as
+// a real HTTP response. It exercises the whole event conversion path locally.
+type EmulatorALB struct {
+	adapter *HandlerAdapterALB
+
+	// MultiValueHeaders mirrors the lambda.multi_value_headers.enabled target group
+	// attribute. It selects both the request fields that are populated and the
+	// response fields that are read back, as ALB does.
+	MultiValueHeaders bool
+
+	// TargetGroupArn is reported in the request context.
+	TargetGroupArn string
+}
+
+// NewEmulatorALB returns an EmulatorALB serving handler through a new HandlerAdapterALB.
+func NewEmulatorALB(handler http.Handler, multiValueHeaders bool) *EmulatorALB {
+	return NewEmulatorWithAdapterALB(NewALB(handler), multiValueHeaders)
+}
+
+// NewEmulatorWithAdapterALB returns an EmulatorALB in front of an existing adapter,
+// so adapter settings such as StripBasePath are exercised too.
+func NewEmulatorWithAdapterALB(adapter *HandlerAdapterALB, multiValueHeaders bool) *EmulatorALB {
+	return &EmulatorALB{
+		adapter:           adapter,
+		MultiValueHeaders: multiValueHeaders,
+		TargetGroupArn:    DefaultEmulatorTargetGroupArn,
+	}
+}
+
+func (e *EmulatorALB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event, err := e.RequestToEvent(r)
+	if err != nil {
+		appLog.Error("Could not convert request to ALB event", "method", r.Method, "url", r.URL, "err", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := e.adapter.ProxyWithContext(context.WithoutCancel(r.Context()), event)
+	if err != nil {
+		// ALB answers a failed Lambda invocation with a 502
+		appLog.Error("Lambda target failed", "method", r.Method, "url", r.URL, "err", err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	e.writeResponse(w, resp)
+}
+
+// RequestToEvent converts an incoming HTTP request into the event ALB would send to a
+// Lambda target. Header names are lower cased, query parameters are passed without
+// decoding and bodies that are not valid UTF-8 are base64 encoded.
+func (e *EmulatorALB) RequestToEvent(r *http.Request) (events.ALBTargetGroupRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.ALBTargetGroupRequest{}, err
+	}
+
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod: r.Method,
+		Path:       r.URL.EscapedPath(),
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: e.TargetGroupArn},
+		},
+	}
+
+	if utf8.Valid(body) {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+
+	headers := make(http.Header)
+	for k, v := range r.Header {
+		headers[strings.ToLower(k)] = v
+	}
+	headers["host"] = []string{r.Host}
+	if r.ContentLength > 0 {
+		headers["content-length"] = []string{strconv.FormatInt(r.ContentLength, 10)}
+	}
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	headers["x-forwarded-proto"] = []string{proto}
+	if _, port, err := net.SplitHostPort(r.Host); err == nil {
+		headers["x-forwarded-port"] = []string{port}
+	} else if proto == "https" {
+		headers["x-forwarded-port"] = []string{"443"}
+	} else {
+		headers["x-forwarded-port"] = []string{"80"}
+	}
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		forwardedFor := append(slices.Clone(headers["x-forwarded-for"]), ip)
+		headers["x-forwarded-for"] = []string{strings.Join(forwardedFor, ", ")}
+	}
+
+	query := splitRawQuery(r.URL.RawQuery)
+
+	if e.MultiValueHeaders {
+		event.MultiValueHeaders = make(map[string][]string, len(headers))
+		for k, v := range headers {
+			event.MultiValueHeaders[k] = v
+		}
+		event.MultiValueQueryStringParameters = make(map[string][]string, len(query))
+		for _, kv := range query {
+			event.MultiValueQueryStringParameters[kv[0]] = append(event.MultiValueQueryStringParameters[kv[0]], kv[1])
+		}
+	} else {
+		// single-value mode keeps only the last value of a repeated header or parameter
+		event.Headers = make(map[string]string, len(headers))
+		for k, v := range headers {
+			event.Headers[k] = v[len(v)-1]
+		}
+		event.QueryStringParameters = make(map[string]string, len(query))
+		for _, kv := range query {
+			event.QueryStringParameters[kv[0]] = kv[1]
+		}
+	}
+
+	return event, nil
+}
+
+// writeResponse writes the ALB target response back to the client, reading the
+// header field that matches the configured header mode.
+func (e *EmulatorALB) writeResponse(w http.ResponseWriter, resp events.ALBTargetGroupResponse) {
+	if e.MultiValueHeaders {
+		for k, values := range resp.MultiValueHeaders {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	} else {
+		// Add, as the cookies of single-value responses arrive under Set-Cookie keys
+		// that differ in case only and canonicalize to the same header
+		for k, v := range resp.Headers {
+			w.Header().Add(k, v)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			appLog.Error("Could not decode base64 response body", "err", err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		body = decoded
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// splitRawQuery splits a raw query string into ordered key value pairs without
+// decoding them, the way ALB forwards query parameters.
+func splitRawQuery(rawQuery string) [][2]string {
+	pairs := make([][2]string, 0)
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		pairs = append(pairs, [2]string{k, v})
+	}
+	return pairs
+}
@@ -0,0 +1,92 @@
+package httpadapter_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EmulatorALB", func() {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+
+	for _, multiValue := range []bool{true, false} {
+		multiValue := multiValue
+
+		Context(fmt.Sprintf("with multi value headers %v", multiValue), func() {
+			It("converts a real request into an ALB event and back", func() {
+				emulator := httpadapter.NewEmulatorALB(echo, multiValue)
+				event, err := emulator.RequestToEvent(httptest.NewRequest("GET", "http://example.com:8080/a%20b?x=1&x=2&y=%20", nil))
+				Expect(err).To(BeNil())
+				Expect(event.Path).To(Equal("/a%20b"))
+				Expect(event.RequestContext.ELB.TargetGroupArn).To(Equal(httpadapter.DefaultEmulatorTargetGroupArn))
+				if multiValue {
+					Expect(event.Headers).To(BeNil())
+					Expect(event.MultiValueHeaders["host"]).To(Equal([]string{"example.com:8080"}))
+					Expect(event.MultiValueHeaders["x-forwarded-port"]).To(Equal([]string{"8080"}))
+					Expect(event.MultiValueQueryStringParameters["x"]).To(Equal([]string{"1", "2"}))
+					Expect(event.MultiValueQueryStringParameters["y"]).To(Equal([]string{"%20"}))
+				} else {
+					Expect(event.MultiValueHeaders).To(BeNil())
+					Expect(event.Headers["host"]).To(Equal("example.com:8080"))
+					Expect(event.QueryStringParameters["x"]).To(Equal("2"))
+					Expect(event.QueryStringParameters["y"]).To(Equal("%20"))
+				}
+			})
+
+			It("builds the request host, scheme and remote address from the forwarded headers", func() {
+				var got *http.Request
+				server := httptest.NewServer(httpadapter.NewEmulatorALB(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = r
+					http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+					http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+					http.SetCookie(w, &http.Cookie{Name: "c", Value: "3"})
+					w.WriteHeader(http.StatusNoContent)
+				}), multiValue))
+				defer server.Close()
+
+				req, _ := http.NewRequest("GET", server.URL+"/who?q=1", nil)
+				req.Header.Set("X-Forwarded-For", "10.0.0.1")
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+
+				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+				Expect(resp.Header.Values("Set-Cookie")).To(ConsistOf("a=1", "b=2", "c=3"))
+				Expect(got.URL.Scheme).To(Equal("http"))
+				Expect(got.URL.Host).To(Equal(strings.TrimPrefix(server.URL, "http://")))
+				Expect(got.Host).To(Equal(got.URL.Host))
+				Expect(got.RemoteAddr).To(Equal("127.0.0.1:0"))
+			})
+
+			It("round trips binary bodies through base64", func() {
+				server := httptest.NewServer(httpadapter.NewEmulatorALB(echo, multiValue))
+				defer server.Close()
+
+				payload := []byte{0xff, 0xfe, 0x00, 0x01}
+				resp, err := http.Post(server.URL+"/upload", "application/octet-stream", bytes.NewReader(payload))
+				Expect(err).To(BeNil())
+				defer resp.Body.Close()
+
+				body, err := io.ReadAll(resp.Body)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+				Expect(body).To(Equal(payload))
+				Expect(resp.Header.Get("X-Path")).To(Equal("/upload"))
+			})
+		})
+	}
+})
@@ -0,0 +1,127 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// BeforeRequestHook is called with the event of every invocation before it is converted
+// into an http.Request, and may modify it, for example to rewrite a legacy path prefix or
+// to add headers. Returning a response short-circuits the invocation: the handler is not
+// called and the response is returned instead. Return nil to continue.
+type BeforeRequestHook[E, R any] func(ctx context.Context, event *E) *R
+
+// AfterResponseHook is called with the event and the response of every invocation before
+// the response is returned to the front end, and may modify the response, for example to
+// add CORS or security headers. It is called for responses of the handler, timeout
+// responses and responses of a BeforeRequestHook, but not when the invocation fails.
+//
+// Responses of API Gateway v1 events, and of ALB events when the target group has multi
+// value headers enabled, carry their headers in MultiValueHeaders and leave Headers nil,
+// so writing to Headers panics. Hooks write MultiValueHeaders when it is not nil:
+//
+//	func(ctx context.Context, e *events.ALBTargetGroupRequest, resp *events.ALBTargetGroupResponse) {
+//		if resp.MultiValueHeaders != nil {
+//			resp.MultiValueHeaders["Access-Control-Allow-Origin"] = []string{"*"}
+//			return
+//		}
+//		if resp.Headers == nil {
+//			resp.Headers = make(map[string]string)
+//		}
+//		resp.Headers["Access-Control-Allow-Origin"] = "*"
+//	}
+type AfterResponseHook[E, R any] func(ctx context.Context, event *E, resp *R)
+
+// WithBeforeRequest adds a hook called before the events of type E are converted. Hooks
+// run in the order they were added, until one returns a response.
+//
+// A hook only applies to the adapters serving E with responses of type R and is ignored
+// by the others, so the options of NewSwitchable can carry hooks for ALB, API Gateway v1
+// and API Gateway v2 events alike. The constructors log a warning for hooks that apply
+// to none of the events of their adapter:
+//
+//	httpadapter.NewALB(mux, httpadapter.WithBeforeRequest(
+//		func(ctx context.Context, e *events.ALBTargetGroupRequest) *events.ALBTargetGroupResponse {
+//			e.Path = strings.TrimPrefix(e.Path, "/legacy")
+//			return nil
+//		}))
+func WithBeforeRequest[E, R any](hook BeforeRequestHook[E, R]) Option {
+	return func(c *config) {
+		c.beforeRequest = append(c.beforeRequest, hook)
+	}
+}
+
+// WithAfterResponse adds a hook called with the responses to events of type E. Hooks run
+// in the order they were added. Like WithBeforeRequest, a hook only applies to the
+// adapters serving E with responses of type R.
+func WithAfterResponse[E, R any](hook AfterResponseHook[E, R]) Option {
+	return func(c *config) {
+		c.afterResponse = append(c.afterResponse, hook)
+	}
+}
+
+// hookTypes reports whether a hook applies to an adapter.
+type hookTypes func(hook interface{}) bool
+
+// hooksFor matches the hooks of the adapters serving E with responses of type R.
+func hooksFor[E, R any]() hookTypes {
+	return func(hook interface{}) bool {
+		switch hook.(type) {
+		case BeforeRequestHook[E, R], AfterResponseHook[E, R]:
+			return true
+		}
+		return false
+	}
+}
+
+// warnUnusedHooks logs a warning for every hook of c that none of types applies to, such
+// as a hook for ALB events passed to NewV2, as it would be ignored silently otherwise.
+func (c *config) warnUnusedHooks(adapter string, types ...hookTypes) {
+	for _, hooks := range [][]interface{}{c.beforeRequest, c.afterResponse} {
+		for _, hook := range hooks {
+			if !slices.ContainsFunc(types, func(matches hookTypes) bool { return matches(hook) }) {
+				appLog.Warn("Hook does not apply to the events of the adapter", "adapter", adapter, "hook", fmt.Sprintf("%T", hook))
+			}
+		}
+	}
+}
+
+// intercept runs proxy for event, surrounded by the hooks of c for events of type E.
+func intercept[E, R any](c *config, ctx context.Context, event E, proxy func(context.Context, E) (R, error)) (R, error) {
+	if len(c.beforeRequest) == 0 && len(c.afterResponse) == 0 {
+		// keeps the event off the heap when there are no hooks
+		return proxy(ctx, event)
+	}
+	return runHooks(c, ctx, event, proxy)
+}
+
+func runHooks[E, R any](c *config, ctx context.Context, event E, proxy func(context.Context, E) (R, error)) (R, error) {
+	var resp R
+	shortCircuit := false
+	for _, h := range c.beforeRequest {
+		hook, ok := h.(BeforeRequestHook[E, R])
+		if !ok {
+			continue
+		}
+		if r := hook(ctx, &event); r != nil {
+			resp, shortCircuit = *r, true
+			break
+		}
+	}
+
+	if !shortCircuit {
+		var err error
+		resp, err = proxy(ctx, event)
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	for _, h := range c.afterResponse {
+		if hook, ok := h.(AfterResponseHook[E, R]); ok {
+			hook(ctx, &event, &resp)
+		}
+	}
+	return resp, nil
+}
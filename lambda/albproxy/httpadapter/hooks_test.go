@@ -0,0 +1,151 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hooks", func() {
+	echoPath := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("X-Tenant")))
+	})
+
+	rewriteLegacy := httpadapter.WithBeforeRequest(func(ctx context.Context, e *events.ALBTargetGroupRequest) *events.ALBTargetGroupResponse {
+		e.Path = strings.TrimPrefix(e.Path, "/legacy")
+		e.Headers["x-tenant"] = "acme"
+		return nil
+	})
+	addCORS := httpadapter.WithAfterResponse(func(ctx context.Context, e *events.ALBTargetGroupRequest, resp *events.ALBTargetGroupResponse) {
+		resp.Headers["Access-Control-Allow-Origin"] = "*"
+	})
+
+	It("modifies the event before conversion and the response before it is returned", func() {
+		adapter := httpadapter.NewALB(echoPath, rewriteLegacy, addCORS)
+
+		resp, err := adapter.ProxyWithContext(context.Background(), albEvent("GET", "/legacy/orders"))
+		Expect(err).To(BeNil())
+		Expect(resp.Body).To(Equal("/orders acme"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Access-Control-Allow-Origin", "*"))
+
+		resp, err = adapter.Proxy(albEvent("GET", "/legacy/users"))
+		Expect(err).To(BeNil())
+		Expect(resp.Body).To(Equal("/users acme"))
+		Expect(resp.Headers).To(HaveKeyWithValue("Access-Control-Allow-Origin", "*"))
+	})
+
+	It("sets headers of ALB events with multi value headers through MultiValueHeaders", func() {
+		adapter := httpadapter.NewALB(echoPath, httpadapter.WithAfterResponse(func(ctx context.Context, e *events.ALBTargetGroupRequest, resp *events.ALBTargetGroupResponse) {
+			if resp.MultiValueHeaders != nil {
+				resp.MultiValueHeaders["Access-Control-Allow-Origin"] = []string{"*"}
+				return
+			}
+			resp.Headers["Access-Control-Allow-Origin"] = "*"
+		}))
+
+		event := albEvent("GET", "/orders")
+		event.MultiValueHeaders = map[string][]string{"host": {"example.com"}, "x-tenant": {"acme"}}
+		event.Headers = nil
+		resp, err := adapter.ProxyWithContext(context.Background(), event)
+		Expect(err).To(BeNil())
+		Expect(resp.Body).To(Equal("/orders acme"))
+		Expect(resp.Headers).To(BeNil())
+		Expect(resp.MultiValueHeaders).To(HaveKeyWithValue("Access-Control-Allow-Origin", []string{"*"}))
+
+		resp, err = adapter.ProxyWithContext(context.Background(), albEvent("GET", "/orders"))
+		Expect(err).To(BeNil())
+		Expect(resp.MultiValueHeaders).To(BeNil())
+		Expect(resp.Headers).To(HaveKeyWithValue("Access-Control-Allow-Origin", "*"))
+	})
+
+	It("short-circuits with the response of a BeforeRequest hook", func() {
+		called := false
+		adapter := httpadapter.NewALB(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}), httpadapter.WithBeforeRequest(func(ctx context.Context, e *events.ALBTargetGroupRequest) *events.ALBTargetGroupResponse {
+			if e.Headers["authorization"] == "" {
+				return &events.ALBTargetGroupResponse{StatusCode: http.StatusUnauthorized, Headers: map[string]string{}}
+			}
+			return nil
+		}), rewriteLegacy, addCORS)
+
+		resp, err := adapter.ProxyWithContext(context.Background(), albEvent("GET", "/legacy/orders"))
+		Expect(err).To(BeNil())
+		Expect(called).To(BeFalse())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(resp.Headers).To(HaveKeyWithValue("Access-Control-Allow-Origin", "*"))
+	})
+
+	It("applies hooks to the adapters of their event type only", func() {
+		v2Hook := httpadapter.WithAfterResponse(func(ctx context.Context, e *events.APIGatewayV2HTTPRequest, resp *events.APIGatewayV2HTTPResponse) {
+			resp.Headers["X-Frame-Options"] = "DENY"
+		})
+		adapter := httpadapter.NewSwitchable(echoPath, addCORS, v2Hook)
+
+		albResp, err := adapter.ProxyWithContext(context.Background(), mustMarshal(albEvent("GET", "/orders")))
+		Expect(err).To(BeNil())
+		Expect(albResp.ALB().Headers).To(HaveKeyWithValue("Access-Control-Allow-Origin", "*"))
+		Expect(albResp.ALB().Headers).ToNot(HaveKey("X-Frame-Options"))
+
+		v2Resp, err := adapter.ProxyWithContext(context.Background(), mustMarshal(events.APIGatewayV2HTTPRequest{
+			Version: "2.0",
+			RawPath: "/orders",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+			},
+		}))
+		Expect(err).To(BeNil())
+		Expect(v2Resp.Version2().Headers).To(HaveKeyWithValue("X-Frame-Options", "DENY"))
+		Expect(v2Resp.Version2().Headers).ToNot(HaveKey("Access-Control-Allow-Origin"))
+	})
+
+	It("applies the hooks of Function URL streaming responses to the stream adapter", func() {
+		streamEvent := func(path string) events.APIGatewayV2HTTPRequest {
+			return events.APIGatewayV2HTTPRequest{
+				RawPath:        path,
+				RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"}},
+			}
+		}
+		adapter := httpadapter.NewStream(echoPath, addCORS,
+			httpadapter.WithBeforeRequest(func(ctx context.Context, e *events.APIGatewayV2HTTPRequest) *events.LambdaFunctionURLStreamingResponse {
+				if e.RawPath == "/blocked" {
+					return &events.LambdaFunctionURLStreamingResponse{StatusCode: http.StatusForbidden, Headers: map[string]string{}, Body: strings.NewReader("forbidden")}
+				}
+				return nil
+			}),
+			httpadapter.WithAfterResponse(func(ctx context.Context, e *events.APIGatewayV2HTTPRequest, resp *events.LambdaFunctionURLStreamingResponse) {
+				resp.Headers["X-Frame-Options"] = "DENY"
+			}))
+
+		resp, err := adapter.ProxyWithContext(context.Background(), streamEvent("/orders"))
+		Expect(err).To(BeNil())
+		Expect(resp.Headers).To(HaveKeyWithValue("X-Frame-Options", "DENY"))
+		Expect(resp.Headers).ToNot(HaveKey("Access-Control-Allow-Origin"))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(Equal("/orders "))
+
+		resp, err = adapter.ProxyWithContext(context.Background(), streamEvent("/blocked"))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		Expect(resp.Headers).To(HaveKeyWithValue("X-Frame-Options", "DENY"))
+		body, err = io.ReadAll(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(Equal("forbidden"))
+	})
+})
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	Expect(err).To(BeNil())
+	return b
+}
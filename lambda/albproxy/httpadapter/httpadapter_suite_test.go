@@ -0,0 +1,13 @@
+package httpadapter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Httpadapter Suite")
+}
@@ -0,0 +1,152 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// DefaultShutdownHookTimeout bounds a shutdown hook registered without a timeout.
+// Lambda allows 500ms for the whole shutdown phase when only the function registers
+// for SIGTERM.
+const DefaultShutdownHookTimeout = 200 * time.Millisecond
+
+// lambdaShutdownBudget bounds all hooks run on SIGTERM inside Lambda, before the
+// runtime sends SIGKILL.
+const lambdaShutdownBudget = 450 * time.Millisecond
+
+// ShutdownHook releases a resource when the process shuts down. It should return
+// once ctx is done.
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name    string
+	timeout time.Duration
+	fn      ShutdownHook
+}
+
+// Lifecycle runs shutdown hooks once, when the process is about to exit. Start runs
+// the hooks of DefaultLifecycle on SIGTERM inside Lambda and after the local server
+// has shut down.
+type Lifecycle struct {
+	mu    sync.Mutex
+	hooks []shutdownHook
+	done  chan struct{} // closed once the hooks have run, nil before Shutdown
+	err   error
+}
+
+// DefaultLifecycle is the Lifecycle used by Start, OnShutdown and OnShutdownClose.
+var DefaultLifecycle = &Lifecycle{}
+
+// OnShutdown registers a hook on DefaultLifecycle, see Lifecycle.OnShutdown.
+func OnShutdown(name string, timeout time.Duration, hook ShutdownHook) {
+	DefaultLifecycle.OnShutdown(name, timeout, hook)
+}
+
+// OnShutdownClose registers a Close on DefaultLifecycle, see Lifecycle.OnShutdownClose.
+func OnShutdownClose(name string, timeout time.Duration, c io.Closer) {
+	DefaultLifecycle.OnShutdownClose(name, timeout, c)
+}
+
+// OnShutdown registers a hook that runs for at most timeout, DefaultShutdownHookTimeout
+// if zero. Hooks run in reverse order of registration, like deferred calls, so a
+// component registered after the database is shut down before it.
+func (l *Lifecycle) OnShutdown(name string, timeout time.Duration, hook ShutdownHook) {
+	if timeout <= 0 {
+		timeout = DefaultShutdownHookTimeout
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, shutdownHook{name: name, timeout: timeout, fn: hook})
+}
+
+// OnShutdownClose registers the Close method of c, such as a database.Service, as a hook.
+func (l *Lifecycle) OnShutdownClose(name string, timeout time.Duration, c io.Closer) {
+	l.OnShutdown(name, timeout, func(ctx context.Context) error {
+		return c.Close()
+	})
+}
+
+// Shutdown runs the hooks, each with its own timeout and within the deadline of ctx.
+// A hook that times out is left running and the next one is started. Only the first
+// call runs the hooks; later calls wait for it and return the same result, or the
+// error of ctx if it is done first. Hooks registered once Shutdown started do not run,
+// and hooks must not call Shutdown, as they would wait for themselves.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	if done := l.done; done != nil {
+		l.mu.Unlock()
+		select {
+		case <-done:
+			return l.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.done = make(chan struct{})
+	hooks := append([]shutdownHook(nil), l.hooks...)
+	l.mu.Unlock()
+
+	// the hooks run unlocked, so they may register hooks, which do not run. A hook
+	// that calls Shutdown waits for the running shutdown to finish, which includes
+	// that hook, so it blocks until its own timeout.
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].run(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	l.err = errors.Join(errs...)
+	close(l.done)
+	return l.err
+}
+
+func (h shutdownHook) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			appLog.Error("Shutdown hook failed", "hook", h.name, "duration", time.Since(start), "err", err)
+			return fmt.Errorf("shutdown hook %s: %w", h.name, err)
+		}
+		appLog.Info("Shutdown hook finished", "hook", h.name, "duration", time.Since(start))
+		return nil
+	case <-ctx.Done():
+		appLog.Error("Shutdown hook timed out", "hook", h.name, "timeout", h.timeout)
+		return fmt.Errorf("shutdown hook %s: %w", h.name, ctx.Err())
+	}
+}
+
+// LambdaOptions returns the lambda.StartWithOptions options that run the hooks on
+// SIGTERM, for functions started without Start:
+//
+//	lambda.StartWithOptions(router.ProxyWithContext, httpadapter.DefaultLifecycle.LambdaOptions()...)
+//
+// SIGTERM is always enabled, so hooks registered after the function started, such as
+// the ones of lazily opened resources, run as well.
+func (l *Lifecycle) LambdaOptions() []lambda.Option {
+	return []lambda.Option{lambda.WithEnableSIGTERM(func() {
+		appLog.Info("Received SIGTERM, running shutdown hooks")
+		ctx, cancel := context.WithTimeout(context.Background(), lambdaShutdownBudget)
+		defer cancel()
+		l.Shutdown(ctx)
+	})}
+}
@@ -0,0 +1,73 @@
+package httpadapter_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type closer struct {
+	closed *[]string
+	name   string
+}
+
+func (c closer) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+var _ = Describe("Lifecycle", func() {
+	It("runs the hooks once in reverse order of registration", func() {
+		var closed []string
+		lifecycle := &httpadapter.Lifecycle{}
+		lifecycle.OnShutdownClose("database", 0, closer{&closed, "database"})
+		lifecycle.OnShutdownClose("cache", 0, closer{&closed, "cache"})
+
+		Expect(lifecycle.Shutdown(context.Background())).To(Succeed())
+		Expect(lifecycle.Shutdown(context.Background())).To(Succeed())
+		Expect(closed).To(Equal([]string{"cache", "database"}))
+	})
+
+	It("moves on when a hook fails or times out", func() {
+		var closed []string
+		lifecycle := &httpadapter.Lifecycle{}
+		lifecycle.OnShutdownClose("database", 0, closer{&closed, "database"})
+		lifecycle.OnShutdown("slow", 10*time.Millisecond, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+		lifecycle.OnShutdown("broken", 0, func(ctx context.Context) error {
+			return errors.New("boom")
+		})
+
+		start := time.Now()
+		err := lifecycle.Shutdown(context.Background())
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+		Expect(err).To(MatchError(ContainSubstring("shutdown hook broken: boom")))
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(closed).To(Equal([]string{"database"}))
+	})
+
+	It("runs the hooks without holding the lifecycle", func() {
+		lifecycle := &httpadapter.Lifecycle{}
+		lifecycle.OnShutdown("reentrant", time.Second, func(ctx context.Context) error {
+			lifecycle.OnShutdown("late", 0, func(ctx context.Context) error { return nil })
+			return lifecycle.Shutdown(ctx)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := lifecycle.Shutdown(ctx)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(lifecycle.Shutdown(context.Background())).To(Equal(err))
+	})
+
+	It("enables SIGTERM before any hook is registered", func() {
+		Expect((&httpadapter.Lifecycle{}).LambdaOptions()).To(HaveLen(1))
+	})
+})
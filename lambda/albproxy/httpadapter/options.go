@@ -0,0 +1,179 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+)
+
+// DefaultDeadlineMargin is the time reserved before the Lambda deadline to
+// return a timeout response instead of being killed by the runtime.
+const DefaultDeadlineMargin = 500 * time.Millisecond
+
+// Option configures a handler adapter.
+type Option func(*config)
+
+// config holds the settings shared by all handler adapters.
+type config struct {
+	deadlineMargin time.Duration
+	payload        core.PayloadOptions
+	telemetry      bool
+	serverTiming   bool
+
+	// BeforeRequestHooks and AfterResponseHooks of any event type, see intercept
+	beforeRequest []interface{}
+	afterResponse []interface{}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		deadlineMargin: DefaultDeadlineMargin,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithDeadlineMargin sets how long before the invocation deadline the handler
+// context is cancelled and a 504 response is returned.
+func WithDeadlineMargin(margin time.Duration) Option {
+	return func(c *config) {
+		c.deadlineMargin = margin
+	}
+}
+
+// WithCompression enables or disables gzip/deflate compression of response
+// bodies for clients that accept it. Compression is enabled by default.
+func WithCompression(enabled bool) Option {
+	return func(c *config) {
+		c.payload.DisableCompression = !enabled
+	}
+}
+
+// WithMaxResponseSize overrides the response payload limit of the front end,
+// 1 MB for ALB and 6 MB for API Gateway and VPC Lattice. The streaming adapter
+// ignores it.
+func WithMaxResponseSize(size int) Option {
+	return func(c *config) {
+		c.payload.MaxSize = size
+	}
+}
+
+// WithBinaryMediaTypes lists the response content types that are returned base64
+// encoded, such as "image/*" or "application/pdf", instead of guessing from
+// whether the body is valid UTF-8. Streamed responses are never base64 encoded,
+// so the streaming adapter ignores it.
+func WithBinaryMediaTypes(mediaTypes ...string) Option {
+	return func(c *config) {
+		c.payload.BinaryMediaTypes = append(c.payload.BinaryMediaTypes, mediaTypes...)
+	}
+}
+
+// WithContentSniffing enables or disables detecting a missing Content-Type from
+// the response body. Sniffing is enabled by default. The streaming adapter
+// sniffs the first chunk written, as the headers are sent with it.
+func WithContentSniffing(enabled bool) Option {
+	return func(c *config) {
+		c.payload.DisableContentSniffing = !enabled
+	}
+}
+
+// WithTelemetry enables or disables logging the cold start, init duration, duration
+// and memory used of every invocation. Telemetry is disabled by default.
+func WithTelemetry(enabled bool) Option {
+	return func(c *config) {
+		c.telemetry = enabled
+	}
+}
+
+// WithServerTiming enables or disables the Server-Timing response header with the
+// invocation duration and, on cold starts, the init duration. Streaming responses
+// never carry it as their headers are sent before the handler returns.
+func WithServerTiming(enabled bool) Option {
+	return func(c *config) {
+		c.serverTiming = enabled
+	}
+}
+
+// payloadOptions returns the payload options for the response to req.
+func (c *config) payloadOptions(req *http.Request) core.PayloadOptions {
+	opts := c.payload
+	opts.AcceptEncoding = req.Header.Get("Accept-Encoding")
+	return opts
+}
+
+// serve runs the handler with a context that expires deadlineMargin before the
+// invocation deadline. It returns false if the handler did not return in time;
+// the handler keeps running in the background and its response must be discarded.
+// Requests without a deadline are served synchronously.
+func (c *config) serve(handler http.Handler, w http.ResponseWriter, req *http.Request) bool {
+	req = withAuthorizerUser(req)
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		handler.ServeHTTP(w, req)
+		return true
+	}
+
+	ctx, cancel := context.WithDeadline(req.Context(), deadline.Add(-c.deadlineMargin))
+	defer cancel()
+
+	done := make(chan *handlerPanic, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- &handlerPanic{value: p, stack: debug.Stack()}
+				return
+			}
+			done <- nil
+		}()
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	}()
+
+	select {
+	case p := <-done:
+		if p != nil {
+			// re-raise in the invocation goroutine so the runtime reports it
+			panic(p)
+		}
+		return true
+	case <-ctx.Done():
+		appLog.Error("Handler did not return before the Lambda deadline",
+			"method", req.Method, "path", req.URL.Path, "traceId", traceID(req),
+			"deadline", deadline, "margin", c.deadlineMargin)
+		return false
+	}
+}
+
+// handlerPanic is re-raised by serve for a panic of the handler goroutine. The
+// runtime only sees the stack of serve, so the message carries the handler's.
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *handlerPanic) Error() string {
+	return fmt.Sprintf("%v\n\nhandler goroutine stack:\n%s", p.value, p.stack)
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (p *handlerPanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// traceID returns the X-Ray trace ID of the request, from the load balancer or
+// API Gateway header or from the Lambda runtime.
+func traceID(req *http.Request) string {
+	if id := req.Header.Get("X-Amzn-Trace-Id"); id != "" {
+		return id
+	}
+	if id, ok := req.Context().Value("x-amzn-trace-id").(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package httpadapter
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// statusRecorder is an http.ResponseWriter for adapters that only need the status
+// of a response. It keeps the beginning of the body for logging.
+type statusRecorder struct {
+	headers http.Header
+	status  int
+	body    bytes.Buffer
+}
+
+func newStatusRecorder() *statusRecorder {
+	return &statusRecorder{
+		headers: make(http.Header),
+	}
+}
+
+func (r *statusRecorder) Header() http.Header {
+	return r.headers
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if room := maxLoggedBodySize - r.body.Len(); room > 0 {
+		r.body.Write(b[:min(room, len(b))])
+	}
+	return len(b), nil
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Flush is a no-op, the response is never sent anywhere.
+func (r *statusRecorder) Flush() {
+	//no-op
+}
+
+// Status returns the response status, 200 if the handler wrote nothing.
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// maxLoggedBodySize bounds the response body kept for logging.
+const maxLoggedBodySize = 1024
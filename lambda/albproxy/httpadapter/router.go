@@ -0,0 +1,139 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// RawHandler handles a raw Lambda event of a single source. The returned value is
+// serialized as the invocation response.
+type RawHandler func(ctx context.Context, event json.RawMessage) (interface{}, error)
+
+// Router lets a single function receive events from several sources. The source of every
+// invocation is detected from the raw payload, see core.DetectEventSource, and the event is
+// dispatched to the handler registered for that source.
+//
+//	router := httpadapter.NewRouter()
+//	router.HandleHTTP(httpadapter.NewSwitchable(mux))
+//	router.HandleSQS(httpadapter.NewSQS(mux))
+//	router.HandleSchedule(jobs)
+//	lambda.Start(router.ProxyWithContext)
+type Router struct {
+	handlers map[core.EventSource]RawHandler
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: make(map[core.EventSource]RawHandler)}
+}
+
+// Handle registers the handler for an event source, replacing any previous one.
+func (r *Router) Handle(source core.EventSource, handler RawHandler) {
+	r.handlers[source] = handler
+}
+
+// HandleHTTP registers the adapter for ALB, API Gateway v1 and API Gateway v2 events.
+func (r *Router) HandleHTTP(h *HandlerAdapterSwitchable) {
+	handler := func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		return h.ProxyWithContext(ctx, event)
+	}
+	r.Handle(core.EventSourceALB, handler)
+	r.Handle(core.EventSourceAPIGatewayV1, handler)
+	r.Handle(core.EventSourceAPIGatewayV2, handler)
+}
+
+// HandleSQS registers the adapter for SQS events.
+func (r *Router) HandleSQS(h *HandlerAdapterSQS) {
+	r.Handle(core.EventSourceSQS, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e events.SQSEvent
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return h.ProxyWithContext(ctx, e)
+	})
+}
+
+// HandleSchedule registers the adapter for EventBridge events.
+func (r *Router) HandleSchedule(h *HandlerAdapterSchedule) {
+	r.Handle(core.EventSourceEventBridge, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e events.CloudWatchEvent
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return nil, h.ProxyWithContext(ctx, e)
+	})
+}
+
+// HandleWebsocket registers the adapter for API Gateway WebSocket events.
+func (r *Router) HandleWebsocket(h *HandlerAdapterWebsocket) {
+	r.Handle(core.EventSourceWebsocket, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e events.APIGatewayWebsocketProxyRequest
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return h.ProxyWithContext(ctx, e)
+	})
+}
+
+// HandleLattice registers the adapter for V1 and V2 VPC Lattice events.
+func (r *Router) HandleLattice(h *HandlerAdapterLattice) {
+	r.Handle(core.EventSourceVPCLattice, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e core.VPCLatticeRequest
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return h.ProxyWithContext(ctx, e)
+	})
+	r.Handle(core.EventSourceVPCLatticeV2, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e core.VPCLatticeRequestV2
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return h.ProxyV2WithContext(ctx, e)
+	})
+}
+
+// HandleSNS registers the handler for SNS events.
+func (r *Router) HandleSNS(handler func(ctx context.Context, event events.SNSEvent) error) {
+	r.Handle(core.EventSourceSNS, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e events.SNSEvent
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return nil, handler(ctx, e)
+	})
+}
+
+// HandleS3 registers the handler for S3 event notifications.
+func (r *Router) HandleS3(handler func(ctx context.Context, event events.S3Event) error) {
+	r.Handle(core.EventSourceS3, func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var e events.S3Event
+		if err := json.Unmarshal(event, &e); err != nil {
+			return nil, err
+		}
+		return nil, handler(ctx, e)
+	})
+}
+
+// ProxyWithContext receives context and a raw event of any source and sends it to the
+// handler registered for that source. Unknown payloads and sources without a handler
+// return an error.
+func (r *Router) ProxyWithContext(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	source := core.DetectEventSource(event)
+	if source == core.EventSourceUnknown {
+		appLog.Error("Could not determine event source", "size", len(event))
+		return nil, core.NewLoggedError("Unknown event source: payload is not an ALB, API Gateway, WebSocket, VPC Lattice, SQS, SNS, S3 or EventBridge event")
+	}
+
+	handler, ok := r.handlers[source]
+	if !ok {
+		appLog.Error("No handler registered for event source", "source", source.String())
+		return nil, core.NewLoggedError("No handler registered for %s events", source)
+	}
+
+	appLog.Debug("Routing event", "source", source.String())
+	return handler(ctx, event)
+}
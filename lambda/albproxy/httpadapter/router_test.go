@@ -0,0 +1,78 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/core"
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Router", func() {
+	var (
+		router *httpadapter.Router
+		sns    []string
+	)
+
+	BeforeEach(func() {
+		sns = nil
+		mux := http.NewServeMux()
+		mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		})
+		mux.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		router = httpadapter.NewRouter()
+		router.HandleHTTP(httpadapter.NewSwitchable(mux))
+		router.HandleSQS(httpadapter.NewSQS(mux))
+		router.HandleLattice(httpadapter.NewLattice(mux))
+		router.HandleSNS(func(ctx context.Context, event events.SNSEvent) error {
+			for _, r := range event.Records {
+				sns = append(sns, r.SNS.Message)
+			}
+			return nil
+		})
+	})
+
+	It("dispatches proxy events to the HTTP adapter", func() {
+		resp, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"version":"2.0","rawPath":"/hello","rawQueryString":"","requestContext":{"http":{"method":"GET"}}}`))
+		Expect(err).To(BeNil())
+		Expect(resp.(*core.SwitchableResponse).Version2().Body).To(Equal("hello"))
+	})
+
+	It("dispatches VPC Lattice V2 events to the Lattice adapter", func() {
+		resp, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"version":"2.0","path":"/hello","method":"GET","headers":{"host":["svc"]},"requestContext":{"targetGroupArn":"arn"}}`))
+		Expect(err).To(BeNil())
+		Expect(resp.(core.VPCLatticeResponse).Body).To(Equal("hello"))
+	})
+
+	It("dispatches SQS events to the SQS adapter", func() {
+		resp, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"Records":[{"messageId":"1","eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:ap-south-1:000000000000:orders","awsRegion":"ap-south-1","body":"{}"}]}`))
+		Expect(err).To(BeNil())
+		Expect(resp.(events.SQSEventResponse).BatchItemFailures).To(BeEmpty())
+	})
+
+	It("dispatches SNS events to the registered handler", func() {
+		_, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"Records":[{"EventSource":"aws:sns","Sns":{"Message":"hi"}}]}`))
+		Expect(err).To(BeNil())
+		Expect(sns).To(Equal([]string{"hi"}))
+	})
+
+	It("rejects sources without a handler", func() {
+		_, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"Records":[{"eventSource":"aws:s3"}]}`))
+		Expect(err).To(MatchError(ContainSubstring("No handler registered for s3 events")))
+	})
+
+	It("rejects unknown payloads", func() {
+		_, err := router.ProxyWithContext(context.Background(), json.RawMessage(`{"hello":"world"}`))
+		Expect(err).To(MatchError(ContainSubstring("Unknown event source")))
+	})
+})
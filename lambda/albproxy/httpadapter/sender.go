@@ -0,0 +1,141 @@
+package httpadapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go/encoding/httpbinding"
+)
+
+// ErrConnectionGone is returned by a ConnectionSender when the client has disconnected.
+var ErrConnectionGone = errors.New("websocket connection gone")
+
+// ConnectionSender sends messages to WebSocket clients.
+type ConnectionSender interface {
+	Send(ctx context.Context, conn WebsocketConnection, data []byte) error
+}
+
+// Broadcast sends data to every connection and deletes the connections that are gone
+// from the registry. It returns the first error other than ErrConnectionGone.
+func Broadcast(ctx context.Context, sender ConnectionSender, registry ConnectionRegistry, conns []WebsocketConnection, data []byte) error {
+	var firstErr error
+	for _, conn := range conns {
+		err := sender.Send(ctx, conn, data)
+		switch {
+		case errors.Is(err, ErrConnectionGone):
+			appLog.Debug("Removing gone websocket connection", "connectionId", conn.ConnectionID)
+			err = registry.Delete(ctx, conn.ConnectionID)
+		case err != nil:
+			appLog.Error("Could not send to websocket connection", "connectionId", conn.ConnectionID, "err", err)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// connectionSenderAPI posts to the API Gateway management API of the connection,
+// signing requests with the credentials of the function.
+type connectionSenderAPI struct {
+	client *http.Client
+	signer *v4.Signer
+	now    func() time.Time
+}
+
+// NewConnectionSenderAPI returns a ConnectionSender that posts to the API Gateway
+// management API at the callback URL of each connection. Requests are signed with
+// the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN of the function
+// for the AWS_REGION, so its role needs execute-api:ManageConnections.
+// A nil client uses http.DefaultClient.
+func NewConnectionSenderAPI(client *http.Client) ConnectionSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &connectionSenderAPI{client: client, signer: v4.NewSigner(), now: time.Now}
+}
+
+func (s *connectionSenderAPI) Send(ctx context.Context, conn WebsocketConnection, data []byte) error {
+	endpoint := strings.TrimSuffix(conn.CallbackURL, "/") + "/@connections/" + httpbinding.EscapePath(conn.ConnectionID, true)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	payloadHash := sha256.Sum256(data)
+	err = s.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "execute-api", os.Getenv("AWS_REGION"), s.now())
+	if err != nil {
+		return fmt.Errorf("could not sign post to connection %s: %w", conn.ConnectionID, err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrConnectionGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBodySize))
+		return fmt.Errorf("post to connection %s failed with status %d: %s", conn.ConnectionID, resp.StatusCode, body)
+	}
+	return nil
+}
+
+// ConnectionSenderMemory is an in-memory ConnectionSender for tests and local runs.
+// It records the messages sent to every connection.
+type ConnectionSenderMemory struct {
+	mu       sync.Mutex
+	messages map[string][][]byte
+	gone     map[string]bool
+}
+
+func NewConnectionSenderMemory() *ConnectionSenderMemory {
+	return &ConnectionSenderMemory{
+		messages: make(map[string][][]byte),
+		gone:     make(map[string]bool),
+	}
+}
+
+func (s *ConnectionSenderMemory) Send(ctx context.Context, conn WebsocketConnection, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gone[conn.ConnectionID] {
+		return ErrConnectionGone
+	}
+	s.messages[conn.ConnectionID] = append(s.messages[conn.ConnectionID], bytes.Clone(data))
+	return nil
+}
+
+// Messages returns the messages sent to a connection, in order.
+func (s *ConnectionSenderMemory) Messages(connectionID string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages[connectionID]
+}
+
+// Disconnect makes further sends to the connection fail with ErrConnectionGone.
+func (s *ConnectionSenderMemory) Disconnect(connectionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gone[connectionID] = true
+}
@@ -0,0 +1,127 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/util"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// RuntimeAPIVariable is set by AWS Lambda in every execution environment.
+// Start uses it to decide whether to register a Lambda handler or run an HTTP server.
+const RuntimeAPIVariable = "AWS_LAMBDA_RUNTIME_API"
+
+// StartOptions configures Start. The zero value serves on $PORT (default 8080)
+// and waits up to 10 seconds for in-flight requests on shutdown.
+type StartOptions struct {
+	// Addr is the listen address used outside Lambda.
+	Addr string
+	// ShutdownTimeout bounds the graceful shutdown of the local server.
+	ShutdownTimeout time.Duration
+	// StripBasePath is removed from the request path before routing in Lambda
+	// and behind the ALB emulator.
+	StripBasePath string
+	// EmulateALB serves the local server through EmulatorALB, so every request
+	// takes the same ALB event conversion path as in Lambda.
+	EmulateALB bool
+	// ALBMultiValueHeaders selects the header mode of the emulated ALB.
+	ALBMultiValueHeaders bool
+	// AdapterOptions configure the ALB adapter in Lambda and behind the emulator.
+	AdapterOptions []Option
+	// Lifecycle holds the shutdown hooks, DefaultLifecycle if nil. They run on SIGTERM
+	// inside Lambda and after the local server has shut down.
+	Lifecycle *Lifecycle
+}
+
+// InLambda reports whether the process runs inside an AWS Lambda execution environment.
+func InLambda() bool {
+	_, ok := os.LookupEnv(RuntimeAPIVariable)
+	return ok
+}
+
+// Start runs handler behind ALB when inside Lambda, and as a plain net/http server
+// everywhere else, so the same middleware chain can be exercised locally.
+// Inside Lambda it never returns. Outside Lambda it returns once the server has been
+// shut down by SIGINT or SIGTERM, or with the error that stopped it.
+func Start(handler http.Handler, opts StartOptions) error {
+	if opts.Lifecycle == nil {
+		opts.Lifecycle = DefaultLifecycle
+	}
+
+	if InLambda() {
+		adapter := NewALB(handler, opts.AdapterOptions...)
+		adapter.StripBasePath(opts.StripBasePath)
+		appLog.Info("Starting Lambda ALB handler")
+		lambda.StartWithOptions(adapter.ProxyWithContext, opts.Lifecycle.LambdaOptions()...)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return serve(ctx, handler, opts)
+}
+
+// serve runs the local server until ctx is done and then shuts it down gracefully.
+// The shutdown hooks run once the server has stopped, however it stopped.
+func serve(ctx context.Context, handler http.Handler, opts StartOptions) error {
+	addr := opts.Addr
+	if addr == "" {
+		addr = ":" + util.GetenvStr("PORT", "8080")
+	}
+	timeout := opts.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if opts.Lifecycle != nil {
+		defer func() {
+			hookCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			opts.Lifecycle.Shutdown(hookCtx)
+		}()
+	}
+
+	if opts.EmulateALB {
+		adapter := NewALB(handler, opts.AdapterOptions...)
+		adapter.StripBasePath(opts.StripBasePath)
+		handler = NewEmulatorWithAdapterALB(adapter, opts.ALBMultiValueHeaders)
+	}
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		appLog.Info("Starting local HTTP server", "addr", addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		appLog.Error("Local HTTP server stopped", "err", err)
+		return err
+	case <-ctx.Done():
+	}
+
+	appLog.Info("Shutting down local HTTP server", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		appLog.Error("Local HTTP server shutdown failed", "err", err)
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	appLog.Info("Local HTTP server stopped")
+	return nil
+}
@@ -0,0 +1,149 @@
+package httpadapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+// startProcessVariable holds the listen address of the Start process, see TestStartProcess.
+const startProcessVariable = "HTTPADAPTER_START_PROCESS"
+
+// TestStartProcess is not a test on its own: the Start specs run it in a child process,
+// as Start installs signal handlers and, inside Lambda, a runtime loop that never ends.
+func TestStartProcess(t *testing.T) {
+	addr, ok := os.LookupEnv(startProcessVariable)
+	if !ok {
+		return
+	}
+
+	lifecycle := &httpadapter.Lifecycle{}
+	lifecycle.OnShutdown("report", 0, func(ctx context.Context) error {
+		fmt.Println("shutdown hook ran")
+		return nil
+	})
+	err := httpadapter.Start(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}), httpadapter.StartOptions{
+		Addr:            addr,
+		ShutdownTimeout: time.Second,
+		StripBasePath:   "/api",
+		EmulateALB:      os.Getenv("EMULATE_ALB") != "",
+		Lifecycle:       lifecycle,
+	})
+	fmt.Println("start returned", err)
+}
+
+// startTestProcess runs the helper test named test in a child process, with env added to
+// the environment of the test, without the AWS_LAMBDA_RUNTIME_API of the test process if
+// it has one. The output of the process is collected in the returned buffer.
+func startTestProcess(test string, env ...string) (*exec.Cmd, *gbytes.Buffer) {
+	cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$")
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, httpadapter.RuntimeAPIVariable+"=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, env...)
+	out := gbytes.NewBuffer()
+	cmd.Stdout, cmd.Stderr = out, out
+	Expect(cmd.Start()).To(Succeed())
+	return cmd, out
+}
+
+var _ = Describe("Start", func() {
+	var (
+		cmd *exec.Cmd
+		out *gbytes.Buffer
+	)
+
+	start := func(addr string, env ...string) {
+		cmd, out = startTestProcess("TestStartProcess", append(env, startProcessVariable+"="+addr)...)
+	}
+
+	freeAddr := func() string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		defer l.Close()
+		return l.Addr().String()
+	}
+
+	get := func(url string) func() (string, error) {
+		return func() (string, error) {
+			resp, err := http.Get(url)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			return string(body), err
+		}
+	}
+
+	AfterEach(func() {
+		if cmd.ProcessState == nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+	})
+
+	It("serves locally until SIGTERM and then runs the shutdown hooks", func() {
+		addr := freeAddr()
+		start(addr)
+
+		Eventually(get("http://"+addr+"/api/orders"), 5*time.Second).Should(Equal("GET /api/orders"))
+
+		Expect(cmd.Process.Signal(syscall.SIGTERM)).To(Succeed())
+		Expect(cmd.Wait()).To(Succeed())
+		Expect(out).To(gbytes.Say("shutdown hook ran"))
+		Expect(out).To(gbytes.Say("start returned <nil>"))
+	})
+
+	It("serves locally through the ALB adapter when emulating the ALB", func() {
+		addr := freeAddr()
+		start(addr, "EMULATE_ALB=1")
+
+		Eventually(get("http://"+addr+"/api/orders"), 5*time.Second).Should(Equal("GET /orders"))
+
+		Expect(cmd.Process.Signal(syscall.SIGINT)).To(Succeed())
+		Expect(cmd.Wait()).To(Succeed())
+		Expect(out).To(gbytes.Say("start returned <nil>"))
+	})
+
+	It("serves ALB events from the Lambda runtime and runs the shutdown hooks on SIGTERM", func() {
+		rt := newFakeRuntime()
+		defer rt.close()
+		start(freeAddr(), httpadapter.RuntimeAPIVariable+"="+strings.TrimPrefix(rt.server.URL, "http://"))
+
+		payload, err := json.Marshal(albEvent("GET", "/api/orders"))
+		Expect(err).To(BeNil())
+		inv := rt.invoke(payload)
+		raw, err := io.ReadAll(inv.body)
+		Expect(err).To(BeNil())
+		var resp events.ALBTargetGroupResponse
+		Expect(json.Unmarshal(raw, &resp)).To(Succeed())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(Equal("GET /orders"))
+
+		Expect(cmd.Process.Signal(syscall.SIGTERM)).To(Succeed())
+		Eventually(out, 5*time.Second).Should(gbytes.Say("shutdown hook ran"))
+		Expect(out).ToNot(gbytes.Say("start returned"))
+	})
+})
@@ -0,0 +1,118 @@
+package httpadapter
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// processStart approximates the start of the execution environment; package
+// variables are initialized before main runs.
+var processStart = time.Now()
+
+// invocations counts the invocations served by this execution environment.
+var invocations atomic.Int64
+
+// invocation holds the telemetry of a single invocation.
+type invocation struct {
+	start        time.Time
+	coldStart    bool
+	initDuration time.Duration
+}
+
+// startInvocation marks the start of an invocation. The first invocation of an
+// execution environment is its cold start, whose init duration is the time from
+// process start to the first event.
+func startInvocation() *invocation {
+	inv := &invocation{start: time.Now()}
+	if invocations.Add(1) == 1 {
+		inv.coldStart = true
+		inv.initDuration = inv.start.Sub(processStart)
+	}
+	return inv
+}
+
+// finishInvocation logs the telemetry of an invocation, with the additional
+// attributes, and adds a Server-Timing header if h is not nil and it is enabled.
+func (c *config) finishInvocation(inv *invocation, h http.Header, attrs ...any) {
+	if !c.telemetry && !c.serverTiming {
+		return
+	}
+	duration := time.Since(inv.start)
+
+	if c.serverTiming && h != nil {
+		h.Add("Server-Timing", inv.serverTiming(duration))
+	}
+	if !c.telemetry {
+		return
+	}
+
+	rss, peak := memoryUsed()
+	attrs = append(attrs,
+		"coldStart", inv.coldStart,
+		"duration", duration,
+		"memoryUsedMB", rss>>20,
+		"memoryPeakMB", peak>>20,
+		"memoryLimitMB", lambdacontext.MemoryLimitInMB,
+	)
+	if inv.coldStart {
+		attrs = append(attrs, "initDuration", inv.initDuration)
+	}
+	appLog.Info("Invocation telemetry", attrs...)
+}
+
+// serverTiming formats the Server-Timing header value, in milliseconds.
+func (inv *invocation) serverTiming(duration time.Duration) string {
+	app := fmt.Sprintf("app;dur=%.1f", float64(duration.Microseconds())/1000)
+	if !inv.coldStart {
+		return app
+	}
+	return fmt.Sprintf("cold, init;dur=%.1f, %s", float64(inv.initDuration.Microseconds())/1000, app)
+}
+
+// memoryUsed returns the resident and peak resident memory of the process in bytes,
+// which is what Lambda reports as memory used. Outside Linux it falls back to the
+// memory mapped by the Go runtime for both.
+func memoryUsed() (rss, peak uint64) {
+	if f, err := os.Open("/proc/self/status"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "VmRSS:"):
+				rss = statusKB(line)
+			case strings.HasPrefix(line, "VmHWM:"):
+				peak = statusKB(line)
+			}
+		}
+		if rss > 0 {
+			return rss, max(rss, peak)
+		}
+	}
+
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		rss = sample[0].Value.Uint64()
+	}
+	return rss, rss
+}
+
+// statusKB parses a "VmRSS:     1234 kB" line of /proc/self/status into bytes.
+func statusKB(line string) uint64 {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return 0
+	}
+	kb, _ := strconv.ParseUint(fields[1], 10, 64)
+	return kb << 10
+}
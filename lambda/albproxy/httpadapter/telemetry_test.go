@@ -0,0 +1,39 @@
+package httpadapter_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rsingh25/tukashi-lib/lambda/albproxy/httpadapter"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Telemetry", func() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Timing", "db;dur=1.5")
+		w.WriteHeader(http.StatusOK)
+	})
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath: "/",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	}
+
+	It("adds the invocation duration to the handler's Server-Timing header", func() {
+		adapter := httpadapter.NewV2(handler, httpadapter.WithTelemetry(true), httpadapter.WithServerTiming(true))
+		resp, err := adapter.ProxyWithContext(context.Background(), event)
+		Expect(err).To(BeNil())
+		Expect(resp.Headers["Server-Timing"]).To(MatchRegexp(`^db;dur=1\.5,(cold, init;dur=[0-9.]+, )?app;dur=[0-9.]+$`))
+	})
+
+	It("leaves the headers alone by default", func() {
+		resp, err := httpadapter.NewV2(handler).ProxyWithContext(context.Background(), event)
+		Expect(err).To(BeNil())
+		Expect(resp.Headers["Server-Timing"]).To(Equal("db;dur=1.5"))
+	})
+})
@@ -1,12 +1,15 @@
 package util
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "time/tzdata"
@@ -159,3 +162,20 @@ func MustToJsonByte(obj any) []byte {
 	}
 	return jsonData
 }
+
+// DecodeJwtPayload returns the JSON payload of a JWT such as the X-Amzn-Oidc-Data header set by AWS LB.
+// The signature is NOT verified, the token must come from a party that already did so.
+func DecodeJwtPayload(token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	// AWS LB pads the segments, other issuers use unpadded base64url
+	payload := strings.TrimRight(parts[1], "=")
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(payload)
+	}
+	return decoded, err
+}
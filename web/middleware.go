@@ -2,14 +2,15 @@ package web
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
-	"strings"
 	"time"
 
+	"github.com/rsingh25/tukashi-lib/util"
+
 	"github.com/justinas/nosurf"
 )
 
@@ -91,18 +92,18 @@ func WithLogging(next http.Handler) http.Handler {
 	})
 }
 
-/* THIS IS NOT POSSIBLE IN LAMBDA
-// ServerSentEventsLogging: This will log a message with initial http request and when response is closed.
+// WithSseLogging logs a message with the initial http request and when the response is closed.
+// In Lambda, Server-Sent Events need a Function URL served by httpadapter.HandlerAdapterStream,
+// the other adapters only return the response once the handler does.
 func WithSseLogging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		logger.Debug("SSE Req Revieved", "method", r.Method, "url", r.URL)
+		appLog.Debug("SSE Req Received", "method", r.Method, "url", r.URL)
 		next.ServeHTTP(w, r)
 		duration := time.Since(start)
-		logger.Debug("SSE Req Completed", "method", r.Method, "url", r.URL, "duration", duration)
+		appLog.Debug("SSE Req Completed", "method", r.Method, "url", r.URL, "duration", duration)
 	})
 }
-*/
 
 /*
 func WithTime(next http.Handler) http.Handler {
@@ -127,11 +128,19 @@ func WithAuth(next http.Handler) http.Handler {
 	})
 }
 
+// ApiKeyHeader is the request header checked by WithApiKey.
+const ApiKeyHeader = "X-API-KEY"
+
+// ValidApiKey reports whether the key sent by the client matches the expected key.
+func ValidApiKey(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
 func WithApiKey(apiKey string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			h1 := r.Header.Get("X-API-KEY")
-			if h1 != apiKey {
+			h1 := r.Header.Get(ApiKeyHeader)
+			if !ValidApiKey(h1, apiKey) {
 				http.Error(w, "invalid api-key", http.StatusForbidden)
 				return
 			}
@@ -140,32 +149,45 @@ func WithApiKey(apiKey string) func(http.Handler) http.Handler {
 	}
 }
 
+// ParseOidcClaims decodes the claims of a JWT, see util.DecodeJwtPayload.
+func ParseOidcClaims(token string) (OidcClaims, error) {
+	var oidcClaim OidcClaims
+
+	oidcDecoded, err := util.DecodeJwtPayload(token)
+	if err != nil {
+		appLog.Error("Error decoding oidcData:", "err", err)
+		return oidcClaim, err
+	}
+
+	err = json.Unmarshal(oidcDecoded, &oidcClaim)
+	if err != nil {
+		appLog.Error("Error unmarshalling oidcData:", "err", err)
+		return oidcClaim, err
+	}
+	return oidcClaim, nil
+}
+
+// WithUser returns a context carrying the user details under UserEmail, UserName, UserPhone and AttmgtRole.
+func WithUser(ctx context.Context, email, name, phone, role string) context.Context {
+	ctx = context.WithValue(ctx, UserEmail{}, email)
+	ctx = context.WithValue(ctx, UserName{}, name)
+	ctx = context.WithValue(ctx, UserPhone{}, phone)
+	ctx = context.WithValue(ctx, AttmgtRole{}, role)
+	return ctx
+}
+
 // This middleware is applicabel to request coming from AWS LB.
 func WithAlbAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		oidcEncoded := strings.Split(r.Header.Get("X-Amzn-Oidc-Data"), ".")[1]
-
-		oidcDecoded, err := base64.StdEncoding.DecodeString(oidcEncoded)
-		if err != nil {
-			appLog.Error("Error decoding oidcData:", "err", err)
-			http.Error(w, "invalid token", http.StatusForbidden)
-			return
-		}
-
-		var oidcClaim OidcClaims
-		err = json.Unmarshal(oidcDecoded, &oidcClaim)
+		oidcClaim, err := ParseOidcClaims(r.Header.Get("X-Amzn-Oidc-Data"))
 		if err != nil {
 			http.Error(w, "invalid token", http.StatusForbidden)
-			appLog.Error("Error unmarshalling oidcData:", "err", err)
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), TraceID{}, r.Header.Get("X-Amzn-Trace-Id"))
-		ctx = context.WithValue(ctx, UserEmail{}, oidcClaim.Email)
-		ctx = context.WithValue(ctx, UserName{}, oidcClaim.Name)
-		ctx = context.WithValue(ctx, UserPhone{}, oidcClaim.PhoneNumber)
-		ctx = context.WithValue(ctx, AttmgtRole{}, oidcClaim.AttmgtRole)
+		ctx = WithUser(ctx, oidcClaim.Email, oidcClaim.Name, oidcClaim.PhoneNumber, oidcClaim.AttmgtRole)
 
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)